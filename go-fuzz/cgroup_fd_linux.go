@@ -0,0 +1,49 @@
+// Copyright 2026 go-fuzz project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+// +build linux,go1.22
+
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"os/exec"
+	"sync/atomic"
+	"syscall"
+)
+
+// cgroupFDUnsupported is set once the kernel refused to start a process in a cgroup (needs Linux 5.7).
+var cgroupFDUnsupported uint32
+
+// prepare makes cmd start the process directly in the cgroup,
+// so that the process and its children never run without limits.
+func (cg *testeeCgroup) prepare(cmd *exec.Cmd) {
+	if cg == nil || atomic.LoadUint32(&cgroupFDUnsupported) != 0 {
+		return
+	}
+	f, err := os.Open(cg.dir)
+	if err != nil {
+		log.Fatalf("failed to open cgroup: %v", err)
+	}
+	cg.f = f
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = new(syscall.SysProcAttr)
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(f.Fd())
+}
+
+// startFailed falls back to moving testees into cgroups after start
+// if the failure says that the kernel can't start them in a cgroup.
+func (cg *testeeCgroup) startFailed(err error) {
+	if cg == nil || cg.f == nil {
+		return
+	}
+	if errors.Is(err, syscall.ENOSYS) || errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.EOPNOTSUPP) {
+		if atomic.CompareAndSwapUint32(&cgroupFDUnsupported, 0, 1) {
+			log.Printf("kernel can't start processes in a cgroup, testees are moved there after start")
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2026 go-fuzz project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const cpuPeriod = 100000 // cpu.max period, in microseconds
+
+var cgroupSeq uint64
+
+// testeeCgroup is a cgroup v2 that limits memory and CPU of a single testee (see -memlimit and -cpuquota).
+type testeeCgroup struct {
+	dir string
+	f   *os.File // open cgroup dir while the testee is started directly in it, see prepare
+}
+
+// initCgroups enables the controllers required by -memlimit and -cpuquota in the -cgroup dir.
+func initCgroups() {
+	if *flagMemLimit == 0 && *flagCPUQuota == 0 {
+		return
+	}
+	if *flagCgroup == "" {
+		log.Fatalf("-memlimit and -cpuquota require -cgroup")
+	}
+	var controllers []string
+	if *flagMemLimit != 0 {
+		controllers = append(controllers, "+memory")
+	}
+	if *flagCPUQuota != 0 {
+		controllers = append(controllers, "+cpu")
+	}
+	// The dir must not contain processes itself, otherwise the kernel refuses
+	// to enable controllers for its children.
+	file := filepath.Join(*flagCgroup, "cgroup.subtree_control")
+	if err := ioutil.WriteFile(file, []byte(strings.Join(controllers, " ")), 0); err != nil {
+		log.Fatalf("failed to enable cgroup controllers in %v: %v", *flagCgroup, err)
+	}
+	removeStaleCgroups()
+}
+
+// removeStaleCgroups removes cgroups left behind by go-fuzz processes
+// that were killed before they could destroy them.
+func removeStaleCgroups() {
+	files, err := ioutil.ReadDir(*flagCgroup)
+	if err != nil {
+		return
+	}
+	for _, f := range files {
+		var pid, seq int
+		if !f.IsDir() {
+			continue
+		}
+		if n, _ := fmt.Sscanf(f.Name(), "go-fuzz-%d-%d", &pid, &seq); n != 2 {
+			continue
+		}
+		if syscall.Kill(pid, 0) != syscall.ESRCH {
+			continue // the owner is still running
+		}
+		cg := &testeeCgroup{dir: filepath.Join(*flagCgroup, f.Name())}
+		cg.destroy()
+	}
+}
+
+// newTesteeCgroup creates a new cgroup with the configured limits,
+// it returns nil if no limits are configured.
+func newTesteeCgroup() *testeeCgroup {
+	if *flagMemLimit == 0 && *flagCPUQuota == 0 {
+		return nil
+	}
+	name := fmt.Sprintf("go-fuzz-%v-%v", os.Getpid(), atomic.AddUint64(&cgroupSeq, 1))
+	cg := &testeeCgroup{dir: filepath.Join(*flagCgroup, name)}
+	if err := os.Mkdir(cg.dir, 0755); err != nil {
+		log.Fatalf("failed to create cgroup: %v", err)
+	}
+	if *flagMemLimit != 0 {
+		cg.write("memory.max", strconv.FormatInt(int64(*flagMemLimit)<<20, 10))
+		// Swapping would turn memory bombs into hangs.
+		ioutil.WriteFile(filepath.Join(cg.dir, "memory.swap.max"), []byte("0"), 0)
+	}
+	if *flagCPUQuota != 0 {
+		cg.write("cpu.max", fmt.Sprintf("%v %v", int64(*flagCPUQuota*cpuPeriod), cpuPeriod))
+	}
+	return cg
+}
+
+func (cg *testeeCgroup) write(file, val string) {
+	if err := ioutil.WriteFile(filepath.Join(cg.dir, file), []byte(val), 0); err != nil {
+		log.Fatalf("failed to write cgroup %v: %v", file, err)
+	}
+}
+
+// add is called after the process started. If it was not started directly
+// in the cgroup, add moves it there. In that case the process runs without
+// limits until then, and children it started before that stay outside.
+func (cg *testeeCgroup) add(pid int) {
+	if cg == nil {
+		return
+	}
+	if cg.f != nil {
+		cg.f.Close()
+		cg.f = nil
+		return
+	}
+	cg.write("cgroup.procs", strconv.Itoa(pid))
+}
+
+// event returns value of the key in a flat keyed cgroup file like memory.events.
+func (cg *testeeCgroup) event(file, key string) string {
+	data, err := ioutil.ReadFile(filepath.Join(cg.dir, file))
+	if err != nil {
+		return ""
+	}
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 2 && fields[0] == key {
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+// oomKilled reports whether the kernel killed a process in the cgroup because of memory limit.
+func (cg *testeeCgroup) oomKilled() bool {
+	if cg == nil {
+		return false
+	}
+	v := cg.event("memory.events", "oom_kill")
+	return v != "" && v != "0"
+}
+
+// destroy kills all processes in the cgroup and removes it.
+func (cg *testeeCgroup) destroy() {
+	if cg == nil {
+		return
+	}
+	if cg.f != nil {
+		cg.f.Close()
+		cg.f = nil
+	}
+	// Kill leftovers that escaped the testee process group (needs Linux 5.14).
+	ioutil.WriteFile(filepath.Join(cg.dir, "cgroup.kill"), []byte("1"), 0)
+	// The kill is asynchronous, the cgroup can't be removed until it is empty.
+	for i := 0; cg.event("cgroup.events", "populated") == "1"; i++ {
+		if i == 100 {
+			log.Printf("processes in cgroup %v are still alive after kill", cg.dir)
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := os.Remove(cg.dir); err != nil {
+		log.Printf("failed to remove cgroup: %v", err)
+	}
+}
@@ -0,0 +1,18 @@
+// Copyright 2026 go-fuzz project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+// +build linux,!go1.22
+
+package main
+
+import (
+	"os/exec"
+)
+
+// prepare does nothing, starting a process directly in a cgroup needs Go 1.22.
+// The testee is moved into the cgroup by add after start instead.
+func (cg *testeeCgroup) prepare(cmd *exec.Cmd) {
+}
+
+func (cg *testeeCgroup) startFailed(err error) {
+}
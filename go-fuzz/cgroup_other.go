@@ -0,0 +1,39 @@
+// Copyright 2026 go-fuzz project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+// +build !linux
+
+package main
+
+import (
+	"log"
+	"os/exec"
+)
+
+type testeeCgroup struct{}
+
+func initCgroups() {
+	if *flagMemLimit != 0 || *flagCPUQuota != 0 {
+		log.Fatalf("-memlimit and -cpuquota are only supported on linux")
+	}
+}
+
+func newTesteeCgroup() *testeeCgroup {
+	return nil
+}
+
+func (cg *testeeCgroup) prepare(cmd *exec.Cmd) {
+}
+
+func (cg *testeeCgroup) startFailed(err error) {
+}
+
+func (cg *testeeCgroup) add(pid int) {
+}
+
+func (cg *testeeCgroup) oomKilled() bool {
+	return false
+}
+
+func (cg *testeeCgroup) destroy() {
+}
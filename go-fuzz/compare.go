@@ -6,5 +6,5 @@
 package main
 
 func compareCoverBody(base, cur []byte) bool {
-	return compareCoverDump(base, cur)
+	return compareCoverWords(base, cur)
 }
@@ -5,21 +5,31 @@ package main
 
 import (
 	"bytes"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
+	"math"
 	"net"
 	"net/http"
 	_ "net/http/pprof"
 	"net/rpc"
+	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/stephens2424/writerset"
+
+	. "github.com/dvyukov/go-fuzz/go-fuzz-defs"
 )
 
 // Coordinator manages persistent fuzzer state like input corpus and crashers.
@@ -30,14 +40,30 @@ type Coordinator struct {
 	corpus       *PersistentSet
 	suppressions *PersistentSet
 	crashers     *PersistentSet
+	hangers      *PersistentSet     // crashers that hang, kept apart from the other ones
+	slowest      *PersistentSet     // slowest inputs, meta is execution time in ns, used with -slowest
+	dupCount     map[Sig]int        // number of stored crashers per suppression, used with -dupmax and -revive
+	triaging     map[Sig]bool       // crashers being checked by -triagecmd
+	triageSem    chan struct{}      // limits concurrent -triagecmd runs
+	revive       []CoordinatorInput // crashers to re-test, used with -revive
+	imports      []CoordinatorInput // inputs to triage, used with -import
+	seed         uint64             // campaign seed, all worker random streams are derived from it
 
 	startTime     time.Time
 	lastInput     time.Time
 	statExecs     uint64
 	statRestarts  uint64
+	statExecTime  map[string]uint64 // testee execution time in ns, by purpose
+	statLatency   [len(latencyBuckets) + 1]uint64
+	statLatencyNs uint64
 	coverFullness int
+	discovery     []discoverySample
+	fuzzedInputs  int               // corpus inputs found by fuzzing (not imported or injected) during this run
+	findings      map[string]uint64 // new crashers found during this run, by class
 
 	statsWriters *writerset.WriterSet
+	eventWriters *writerset.WriterSet
+	events       chan coordinatorEvent // nil if -http is not set
 }
 
 // CoordinatorWorker represents coordinator's view of a worker.
@@ -45,6 +71,8 @@ type CoordinatorWorker struct {
 	id       int
 	procs    int
 	pending  []CoordinatorInput
+	suppress [][]byte           // new suppressions, used with -triagecmd
+	deferred []CoordinatorInput // revived crashers and imports given to the worker, re-queued if it dies
 	lastSync time.Time
 }
 
@@ -52,18 +80,71 @@ type CoordinatorWorker struct {
 func coordinatorMain(ln net.Listener) {
 	m := &Coordinator{}
 	m.statsWriters = writerset.New()
+	m.eventWriters = writerset.New()
 	m.startTime = time.Now()
+	m.seed = *flagSeed
+	if m.seed == 0 {
+		m.seed = uint64(m.startTime.UnixNano())
+	}
+	log.Printf("using seed %v", m.seed)
 	m.lastInput = time.Now()
+	m.findings = make(map[string]uint64)
+	m.statExecTime = make(map[string]uint64)
 	m.suppressions = newPersistentSet(filepath.Join(*flagWorkdir, "suppressions"))
 	m.crashers = newPersistentSet(filepath.Join(*flagWorkdir, "crashers"))
+	m.hangers = newPersistentSet(filepath.Join(*flagWorkdir, "hangers"))
 	m.corpus = newPersistentSet(filepath.Join(*flagWorkdir, "corpus"))
+	if err := ioutil.WriteFile(filepath.Join(*flagWorkdir, "seed"), []byte(fmt.Sprintf("%v\n", m.seed)), 0660); err != nil {
+		log.Printf("failed to write seed: %v", err)
+	}
+	if *flagSlowest > 0 {
+		m.slowest = newPersistentSet(filepath.Join(*flagWorkdir, "slowest"))
+	}
+	if *flagRevive {
+		for _, set := range []*PersistentSet{m.crashers, m.hangers} {
+			for _, a := range set.m {
+				m.revive = append(m.revive, CoordinatorInput{a.data, 0, execRevive, true, true})
+			}
+		}
+	}
+	if *flagImport != "" {
+		m.imports = readImports(*flagImport, m.corpus)
+	}
+	m.dupCount = make(map[Sig]int)
+	m.triaging = make(map[Sig]bool)
+	m.triageSem = make(chan struct{}, triageMaxProcs)
+	if *flagDup && *flagDupMax > 0 || *flagRevive {
+		for _, set := range []*PersistentSet{m.crashers, m.hangers} {
+			for sig := range set.m {
+				out, err := set.description(sig, "output")
+				if err != nil {
+					continue
+				}
+				m.dupCount[hash(extractSuppression(out))]++
+			}
+		}
+	}
 	if len(m.corpus.m) == 0 {
 		m.corpus.add(Artifact{[]byte{}, 0, false})
 	}
+	if *flagSyncDir != "" {
+		if err := os.MkdirAll(*flagSyncDir, 0770); err != nil {
+			log.Fatalf("failed to create sync dir: %v", err)
+		}
+		for _, a := range m.corpus.m {
+			exportSyncDir(a.data)
+		}
+	}
 
 	m.workers = make(map[int]*CoordinatorWorker)
 	coordinatorListen(m)
 
+	addShutdownCleanup(func() {
+		if m.writeSummary() {
+			atomic.StoreInt32(&exitStatus, 1)
+		}
+	})
+
 	go coordinatorLoop(m)
 
 	s := rpc.NewServer()
@@ -74,8 +155,16 @@ func coordinatorMain(ln net.Listener) {
 func coordinatorListen(c *Coordinator) {
 	if *flagHTTP != "" {
 		http.HandleFunc("/eventsource", c.eventSource)
+		http.HandleFunc("/events", c.eventStream)
+		http.HandleFunc("/metrics", c.metrics)
+		if *flagInjectToken != "" {
+			http.HandleFunc("/inject", c.injectInput)
+		}
 		http.HandleFunc("/", c.index)
 
+		c.events = make(chan coordinatorEvent, 1000)
+		go c.eventLoop()
+
 		go func() {
 			fmt.Printf("Serving statistics on http://%s/\n", *flagHTTP)
 			panic(http.ListenAndServe(*flagHTTP, nil))
@@ -86,18 +175,37 @@ func coordinatorListen(c *Coordinator) {
 }
 
 func coordinatorLoop(c *Coordinator) {
+	syncDirSeen := make(map[string]bool)
+	var lastSyncDir, lastDiscovery time.Time
 	for range time.NewTicker(3 * time.Second).C {
 		if atomic.LoadUint32(&shutdown) != 0 {
 			return
 		}
+		if *flagSyncDir != "" && time.Since(lastSyncDir) >= syncDirPeriod {
+			lastSyncDir = time.Now()
+			c.importSyncDir(syncDirSeen)
+		}
 		c.mu.Lock()
 		// Nuke dead workers.
 		for id, s := range c.workers {
 			if time.Since(s.lastSync) < syncDeadline {
 				continue
 			}
-			log.Printf("worker %v died", s.id)
+			logEvent("worker_died", map[string]interface{}{"worker": s.id}, "worker %v died", s.id)
 			delete(c.workers, id)
+			c.requeueDeferred(s)
+		}
+		if time.Since(lastDiscovery) >= discoveryPeriod {
+			lastDiscovery = time.Now()
+			var cpu uint64
+			for _, ns := range c.statExecTime {
+				cpu += ns
+			}
+			c.discovery = addDiscoverySample(c.discovery, discoverySample{lastDiscovery, c.fuzzedInputs, time.Duration(cpu)})
+			if rate, ok := discoveryRate(c.discovery); ok && rate < *flagStopRate {
+				log.Printf("found %.1f new inputs per CPU-hour during the last hour, which is below -stoprate, stopping", rate)
+				requestShutdown()
+			}
 		}
 		c.mu.Unlock()
 
@@ -109,7 +217,7 @@ func (c *Coordinator) broadcastStats() {
 	stats := c.coordinatorStats()
 
 	// log to stdout
-	log.Println(stats.String())
+	logEvent("stats", stats.logFields(), "%v", stats)
 
 	// write to any http clients
 	b, err := json.Marshal(stats)
@@ -127,6 +235,87 @@ func (c *Coordinator) eventSource(w http.ResponseWriter, r *http.Request) {
 	<-c.statsWriters.Add(w)
 }
 
+// coordinatorEvent describes a new corpus input or crasher for subscribers of /events.
+type coordinatorEvent struct {
+	Type    string // "input" or "crasher"
+	Sig     string // hash of data, same as file name in workdir
+	Data    []byte
+	Output  []byte `json:",omitempty"`
+	Hanging bool   `json:",omitempty"`
+	Time    time.Time
+}
+
+// publishEvent queues the event for /events subscribers.
+// The event is dropped if subscribers don't keep up.
+func (c *Coordinator) publishEvent(typ string, data, output []byte, hanging bool) {
+	if c.events == nil {
+		return
+	}
+	sig := hash(data)
+	ev := coordinatorEvent{typ, hex.EncodeToString(sig[:]), data, output, hanging, time.Now()}
+	select {
+	case c.events <- ev:
+	default:
+		if *flagV >= 1 {
+			log.Printf("dropping %v event, subscribers are too slow", typ)
+		}
+	}
+}
+
+func (c *Coordinator) eventLoop() {
+	for ev := range c.events {
+		b, err := json.Marshal(ev)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Fprintf(c.eventWriters, "event: %s\ndata: %s\n\n", ev.Type, b)
+		c.eventWriters.Flush()
+	}
+}
+
+// eventStream streams new corpus inputs and crashers as server-sent events.
+func (c *Coordinator) eventStream(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	<-c.eventWriters.Add(w)
+}
+
+// injectInput queues an input POSTed by user for triage on one of the workers.
+// Workers triage inputs received from coordinator before anything else.
+// If the input gives new coverage, it's added to corpus and appears in /events.
+func (c *Coordinator) injectInput(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "use POST", http.StatusMethodNotAllowed)
+		return
+	}
+	auth := []byte(r.Header.Get("Authorization"))
+	if subtle.ConstantTimeCompare(auth, []byte("Bearer "+*flagInjectToken)) != 1 {
+		http.Error(w, "bad token", http.StatusUnauthorized)
+		return
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(r.Body, MaxInputSize+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(data) > MaxInputSize {
+		http.Error(w, "input is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Any worker will do, the input is broadcast to others if it's interesting.
+	for _, cw := range c.workers {
+		cw.pending = append(cw.pending, CoordinatorInput{data, 0, execCorpus, false, false})
+		sig := hash(data)
+		log.Printf("injected input %x queued on worker %v", sig[:], cw.id)
+		fmt.Fprintf(w, "%x\n", sig[:])
+		return
+	}
+	http.Error(w, "no workers connected", http.StatusServiceUnavailable)
+}
+
 func (c *Coordinator) index(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path == "/" {
 		r.URL.Path = "/stats.html"
@@ -140,12 +329,20 @@ func (c *Coordinator) coordinatorStats() coordinatorStats {
 
 	stats := coordinatorStats{
 		Corpus:           uint64(len(c.corpus.m)),
-		Crashers:         uint64(len(c.crashers.m)),
+		Crashers:         uint64(len(c.crashers.m) + len(c.hangers.m)),
 		Uptime:           fmtDuration(time.Since(c.startTime)),
 		StartTime:        c.startTime,
 		LastNewInputTime: c.lastInput,
 		Execs:            c.statExecs,
 		Cover:            uint64(c.coverFullness),
+		CPUHours:         make(map[string]float64),
+		DiscoveryRate:    -1,
+	}
+	for purpose, ns := range c.statExecTime {
+		stats.CPUHours[purpose] = time.Duration(ns).Hours()
+	}
+	if rate, ok := discoveryRate(c.discovery); ok {
+		stats.DiscoveryRate = rate
 	}
 
 	// Print stats line.
@@ -164,6 +361,8 @@ type coordinatorStats struct {
 	Workers, Corpus, Crashers, Execs, Cover, RestartsDenom uint64
 	LastNewInputTime, StartTime                            time.Time
 	Uptime                                                 string
+	CPUHours                                               map[string]float64 // testee execution time, by purpose
+	DiscoveryRate                                          float64            // new inputs per testee CPU-hour during the last hour, -1 if unknown
 }
 
 func (s coordinatorStats) String() string {
@@ -175,10 +374,108 @@ func (s coordinatorStats) String() string {
 	)
 }
 
+// logFields returns the stats as separate fields of the stats log event.
+func (s coordinatorStats) logFields() map[string]interface{} {
+	fields := map[string]interface{}{
+		"workers":                s.Workers,
+		"corpus":                 s.Corpus,
+		"crashers":               s.Crashers,
+		"execs":                  s.Execs,
+		"execs_per_sec":          s.ExecsPerSec(),
+		"execs_per_restart":      s.RestartsDenom,
+		"cover":                  s.Cover,
+		"uptime_seconds":         time.Since(s.StartTime).Seconds(),
+		"last_new_input_seconds": time.Since(s.LastNewInputTime).Seconds(),
+	}
+	if s.DiscoveryRate >= 0 {
+		fields["discovery_rate"] = s.DiscoveryRate
+	}
+	return fields
+}
+
 func (s coordinatorStats) ExecsPerSec() float64 {
 	return float64(s.Execs) * 1e9 / float64(time.Since(s.StartTime))
 }
 
+// Classes of findings reported in the run summary and accepted by -failon.
+const (
+	findingCrash = "crash"
+	findingHang  = "hang"
+	findingOOM   = "oom"
+)
+
+func findingClass(a *NewCrasherArgs) string {
+	if a.Hanging {
+		return findingHang
+	}
+	if bytes.HasPrefix(a.Error, []byte(oomHeader)) {
+		return findingOOM
+	}
+	return findingCrash
+}
+
+// parseFailOn parses the -failon flag value into a list of finding classes.
+func parseFailOn(s string) ([]string, error) {
+	var classes []string
+	for _, class := range strings.Split(s, ",") {
+		class = strings.TrimSpace(class)
+		switch class {
+		case "":
+			continue
+		case findingCrash, findingHang, findingOOM:
+			classes = append(classes, class)
+		default:
+			return nil, fmt.Errorf("unknown finding class %q", class)
+		}
+	}
+	return classes, nil
+}
+
+// coordinatorSummary is the final machine-readable report of a run.
+type coordinatorSummary struct {
+	coordinatorStats
+	Seed     uint64
+	Findings map[string]uint64
+	Slowest  map[string]uint64 `json:",omitempty"` // execution time in ns by input hash
+	Failed   bool
+}
+
+// writeSummary saves final statistics and findings of this run into workdir/summary.json.
+// It returns true if the run must fail according to -failon.
+func (c *Coordinator) writeSummary() bool {
+	s := coordinatorSummary{
+		coordinatorStats: c.coordinatorStats(),
+		Seed:             c.seed,
+		Findings:         make(map[string]uint64),
+	}
+	c.mu.Lock()
+	for class, n := range c.findings {
+		s.Findings[class] = n
+	}
+	if c.slowest != nil {
+		s.Slowest = make(map[string]uint64)
+		for sig, a := range c.slowest.m {
+			s.Slowest[hex.EncodeToString(sig[:])] = a.meta
+		}
+	}
+	c.mu.Unlock()
+	classes, _ := parseFailOn(*flagFailOn)
+	for _, class := range classes {
+		if s.Findings[class] != 0 {
+			log.Printf("run failed: found %v new %v(s)", s.Findings[class], class)
+			s.Failed = true
+		}
+	}
+	data, err := json.MarshalIndent(s, "", "\t")
+	if err != nil {
+		panic(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(*flagWorkdir, "summary.json"), data, 0660); err != nil {
+		log.Printf("failed to write summary: %v", err)
+	}
+	return s.Failed
+}
+
 func fmtDuration(d time.Duration) string {
 	if d.Hours() >= 1 {
 		return fmt.Sprintf("%vh%vm", int(d.Hours()), int(d.Minutes())%60)
@@ -194,8 +491,11 @@ type ConnectArgs struct {
 }
 
 type ConnectRes struct {
-	ID     int
-	Corpus []CoordinatorInput
+	ID       int
+	Seed     uint64
+	Corpus   []CoordinatorInput
+	Deferred []CoordinatorInput // inputs to triage once Corpus is triaged
+	Triage   bool               // crashers are checked by -triagecmd, suppressions come with Sync
 }
 
 // CoordinatorInput is description of input that is passed between coordinator and worker.
@@ -220,17 +520,36 @@ func (c *Coordinator) Connect(a *ConnectArgs, r *ConnectRes) error {
 	}
 	c.workers[w.id] = w
 	r.ID = w.id
+	r.Seed = c.seed
+	r.Triage = *flagTriageCmd != ""
 	// Give the worker initial corpus.
-	for _, a := range c.corpus.m {
+	// Map order is random, so sort it to triage it in the same order in runs with the same -seed.
+	sigs := make([]Sig, 0, len(c.corpus.m))
+	for sig := range c.corpus.m {
+		sigs = append(sigs, sig)
+	}
+	sort.Slice(sigs, func(i, j int) bool { return bytes.Compare(sigs[i][:], sigs[j][:]) < 0 })
+	for _, sig := range sigs {
+		a := c.corpus.m[sig]
 		r.Corpus = append(r.Corpus, CoordinatorInput{a.data, a.meta, execCorpus, !a.user, true})
 	}
+	// Crashers and imported inputs need to be tested only once, so give them to the first worker.
+	// They are triaged against coverage of the whole corpus, so that only imports
+	// that give new coverage are added to it.
+	r.Deferred = append(r.Deferred, c.revive...)
+	c.revive = nil
+	r.Deferred = append(r.Deferred, c.imports...)
+	c.imports = nil
+	w.deferred = r.Deferred
 	return nil
 }
 
 type NewInputArgs struct {
-	ID   int
-	Data []byte
-	Prio uint64
+	ID      int
+	Data    []byte
+	Prio    uint64
+	Revived bool // old crasher that does not crash anymore
+	Fuzzed  bool // found by mutating other inputs, as opposed to coming from corpus, -import or /inject
 }
 
 // NewInput saves new interesting input on coordinator.
@@ -243,11 +562,21 @@ func (c *Coordinator) NewInput(a *NewInputArgs, r *int) error {
 		return errors.New("unknown worker")
 	}
 
+	if a.Revived {
+		c.retireCrasher(a.Data)
+	}
 	art := Artifact{a.Data, a.Prio, false}
 	if !c.corpus.add(art) {
 		return nil
 	}
 	c.lastInput = time.Now()
+	if a.Fuzzed {
+		c.fuzzedInputs++
+	}
+	c.publishEvent("input", a.Data, nil, false)
+	if *flagSyncDir != "" {
+		exportSyncDir(a.Data)
+	}
 	// Queue the input for sending to every worker.
 	for _, w1 := range c.workers {
 		w1.pending = append(w1.pending, CoordinatorInput{a.Data, a.Prio, execCorpus, true, w1 != w})
@@ -256,11 +585,111 @@ func (c *Coordinator) NewInput(a *NewInputArgs, r *int) error {
 	return nil
 }
 
+// readImports reads all files in dir (recursively) that are not in corpus yet.
+// Every file is one input, the ones that give new coverage end up in corpus.
+func readImports(dir string, corpus *PersistentSet) []CoordinatorInput {
+	var inputs []CoordinatorInput
+	seen := make(map[Sig]bool)
+	tooLarge := 0
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		if info.Size() > MaxInputSize {
+			tooLarge++
+			return nil
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		sig := hash(data)
+		if _, ok := corpus.m[sig]; ok || seen[sig] {
+			return nil
+		}
+		seen[sig] = true
+		inputs = append(inputs, CoordinatorInput{data, 0, execCorpus, false, false})
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("failed to import inputs: %v", err)
+	}
+	if tooLarge != 0 {
+		log.Printf("skipped %v files larger than %v bytes", tooLarge, MaxInputSize)
+	}
+	log.Printf("importing %v inputs from %v", len(inputs), dir)
+	return inputs
+}
+
+// requeueDeferred gives revived crashers and imports of a dead worker to another worker,
+// or to the next worker that connects. The ones that were already processed are skipped.
+func (c *Coordinator) requeueDeferred(dead *CoordinatorWorker) {
+	var inputs []CoordinatorInput
+	for _, input := range dead.deferred {
+		sig := hash(input.Data)
+		if input.Type == execRevive {
+			_, crasher := c.crashers.m[sig]
+			_, hanger := c.hangers.m[sig]
+			if crasher || hanger {
+				inputs = append(inputs, input)
+			}
+		} else if _, ok := c.corpus.m[sig]; !ok {
+			inputs = append(inputs, input)
+		}
+	}
+	if len(inputs) == 0 {
+		return
+	}
+	for _, w := range c.workers {
+		w.pending = append(w.pending, inputs...)
+		w.deferred = append(w.deferred, inputs...)
+		return
+	}
+	for _, input := range inputs {
+		if input.Type == execRevive {
+			c.revive = append(c.revive, input)
+		} else {
+			c.imports = append(c.imports, input)
+		}
+	}
+}
+
+// retireCrasher removes a crasher that does not crash anymore, and its suppression
+// unless other stored crashers (see -dup) have the same one.
+// The crasher output is kept in corpus as a description of the input origin.
+func (c *Coordinator) retireCrasher(data []byte) {
+	sig := hash(data)
+	set := c.crashers
+	if _, ok := set.m[sig]; !ok {
+		set = c.hangers
+		if _, ok := set.m[sig]; !ok {
+			return
+		}
+	}
+	out, err := set.description(sig, "output")
+	if err == nil {
+		suppSig := hash(extractSuppression(out))
+		if c.dupCount[suppSig] > 0 {
+			c.dupCount[suppSig]--
+		}
+		if c.dupCount[suppSig] == 0 {
+			c.suppressions.remove(suppSig)
+		}
+		c.corpus.addDescription(data, out, "revived")
+	}
+	set.remove(sig)
+	log.Printf("crasher %x does not crash anymore, moved it to corpus", sig[:])
+}
+
 type NewCrasherArgs struct {
 	Data        []byte
 	Error       []byte
 	Suppression []byte
 	Hanging     bool
+	Env         []string // testee environment set by go-fuzz, see testeeEnv
 }
 
 // NewCrasher saves new crasher input on coordinator.
@@ -268,12 +697,84 @@ func (c *Coordinator) NewCrasher(a *NewCrasherArgs, r *int) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if *flagTriageCmd == "" {
+		c.storeCrasher(a)
+		return nil
+	}
+	if _, known := c.suppressions.m[hash(a.Suppression)]; known && !*flagDup {
+		return nil
+	}
+	key := hash(a.Suppression)
+	if *flagDup {
+		key = hash(a.Data)
+	}
+	if c.triaging[key] {
+		return nil
+	}
+	select {
+	case c.triageSem <- struct{}{}:
+	default:
+		// With -dup every distinct input gets its own run, so a crash that is hit
+		// often would start unbounded number of commands. The crasher is reported
+		// again next time it is found, because hubs don't suppress it by themselves.
+		if *flagV >= 1 {
+			log.Printf("too many triage commands are running, dropping crasher %x", hash(a.Data))
+		}
+		return nil
+	}
+	// The command can take a while, don't stall the calling hub.
+	c.triaging[key] = true
+	go func() {
+		verdict := runTriageCmd(a)
+		<-c.triageSem
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.triaging, key)
+		switch verdict {
+		case triageStore:
+			if c.storeCrasher(a) && !*flagDup {
+				c.broadcastSuppression(a.Suppression)
+			}
+		case triageSuppress:
+			c.suppressions.add(Artifact{a.Suppression, 0, false})
+			c.broadcastSuppression(a.Suppression)
+		}
+	}()
+	return nil
+}
+
+// broadcastSuppression sends the suppression to all workers with the next Sync.
+// With -triagecmd workers don't suppress crashers by themselves,
+// because crashers with a dropped signature still need to be reported.
+func (c *Coordinator) broadcastSuppression(supp []byte) {
+	for _, w := range c.workers {
+		w.suppress = append(w.suppress, supp)
+	}
+}
+
+// storeCrasher saves the crasher unless it is a duplicate and reports whether it was saved.
+// The caller must hold c.mu.
+func (c *Coordinator) storeCrasher(a *NewCrasherArgs) bool {
 	if !*flagDup && !c.suppressions.add(Artifact{a.Suppression, 0, false}) {
-		return nil // Already have this.
+		return false // Already have this.
+	}
+	suppSig := hash(a.Suppression)
+	if *flagDup && *flagDupMax > 0 && c.dupCount[suppSig] >= *flagDupMax {
+		return false // Have enough crashers with this signature.
 	}
-	if !c.crashers.add(Artifact{a.Data, 0, false}) {
-		return nil // Already have this.
+	set := c.crashers
+	if a.Hanging {
+		set = c.hangers
 	}
+	if !set.add(Artifact{a.Data, 0, false}) {
+		return false // Already have this.
+	}
+	c.dupCount[suppSig]++
+	class := findingClass(a)
+	c.findings[class]++
+	sig := hash(a.Data)
+	logEvent("crasher", map[string]interface{}{"sig": hex.EncodeToString(sig[:]), "class": class, "size": len(a.Data)},
+		"new %v: crasher %x", class, sig[:])
 
 	// Prepare quoted version of input to simplify creation of standalone reproducers.
 	var buf bytes.Buffer
@@ -288,10 +789,14 @@ func (c *Coordinator) NewCrasher(a *NewCrasherArgs, r *int) error {
 		}
 		fmt.Fprintf(&buf, "\n")
 	}
-	c.crashers.addDescription(a.Data, buf.Bytes(), "quoted")
-	c.crashers.addDescription(a.Data, a.Error, "output")
-
-	return nil
+	set.addDescription(a.Data, buf.Bytes(), "quoted")
+	set.addDescription(a.Data, a.Error, "output")
+	set.addDescription(a.Data, []byte(fmt.Sprintf("%v\n", c.seed)), "seed")
+	if len(a.Env) != 0 {
+		set.addDescription(a.Data, []byte(strings.Join(a.Env, "\n")+"\n"), "env")
+	}
+	c.publishEvent("crasher", a.Data, a.Error, a.Hanging)
+	return true
 }
 
 type SyncArgs struct {
@@ -299,10 +804,21 @@ type SyncArgs struct {
 	Execs         uint64
 	Restarts      uint64
 	CoverFullness int
+	ExecTime      map[string]uint64 // testee execution time in ns, by purpose
+	Slowest       []SlowInput       // new slowest inputs seen by the worker, used with -slowest
+	Latency       []uint64          // histogram of execution times, see latencyBuckets
+	LatencyNs     uint64            // sum of execution times
+}
+
+// SlowInput is an input along with the time it took to execute.
+type SlowInput struct {
+	Data []byte
+	Ns   uint64
 }
 
 type SyncRes struct {
-	Inputs []CoordinatorInput // new interesting inputs
+	Inputs       []CoordinatorInput // new interesting inputs
+	Suppressions [][]byte           // new suppressions, used with -triagecmd
 }
 
 var errUnkownWorker = errors.New("unknown worker")
@@ -319,11 +835,52 @@ func (c *Coordinator) Sync(a *SyncArgs, r *SyncRes) error {
 	}
 	c.statExecs += a.Execs
 	c.statRestarts += a.Restarts
+	for purpose, ns := range a.ExecTime {
+		c.statExecTime[purpose] += ns
+	}
+	for i, n := range a.Latency {
+		if i < len(c.statLatency) {
+			c.statLatency[i] += n
+		}
+	}
+	c.statLatencyNs += a.LatencyNs
 	if c.coverFullness < a.CoverFullness {
 		c.coverFullness = a.CoverFullness
 	}
+	for _, s := range a.Slowest {
+		c.noteSlow(s)
+	}
 	w.lastSync = time.Now()
 	r.Inputs = w.pending
 	w.pending = nil
+	r.Suppressions = w.suppress
+	w.suppress = nil
 	return nil
 }
+
+// noteSlow adds the input to workdir/slowest if it is slower than
+// the fastest input stored there, or if there is still room.
+func (c *Coordinator) noteSlow(s SlowInput) {
+	if c.slowest == nil {
+		return
+	}
+	sig := hash(s.Data)
+	if a, ok := c.slowest.m[sig]; ok {
+		if a.meta >= s.Ns {
+			return
+		}
+		c.slowest.remove(sig)
+	} else if len(c.slowest.m) >= *flagSlowest {
+		fastest, fastestNs := Sig{}, uint64(math.MaxUint64)
+		for sig, a := range c.slowest.m {
+			if a.meta < fastestNs {
+				fastest, fastestNs = sig, a.meta
+			}
+		}
+		if fastestNs >= s.Ns {
+			return
+		}
+		c.slowest.remove(fastest)
+	}
+	c.slowest.add(Artifact{s.Data, s.Ns, false})
+}
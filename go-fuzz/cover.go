@@ -4,6 +4,7 @@
 package main
 
 import (
+	"encoding/binary"
 	"fmt"
 	"log"
 	"os"
@@ -41,6 +42,33 @@ func compareCoverDump(base, cur []byte) bool {
 	return false
 }
 
+// compareCoverWords is the same as compareCoverDump, but processes 8 bytes at a time.
+// Cover tables are sparse, so most words are either zero or equal in base and cur,
+// and only the remaining ones need to be compared byte by byte.
+func compareCoverWords(base, cur []byte) bool {
+	i := 0
+	for ; i+8 <= len(cur); i += 8 {
+		c := binary.LittleEndian.Uint64(cur[i:])
+		if c == 0 {
+			continue
+		}
+		if c == binary.LittleEndian.Uint64(base[i:]) {
+			continue
+		}
+		for j := i; j < i+8; j++ {
+			if cur[j] > base[j] {
+				return true
+			}
+		}
+	}
+	for ; i < len(cur); i++ {
+		if cur[i] > base[i] {
+			return true
+		}
+	}
+	return false
+}
+
 func updateMaxCover(base, cur []byte) int {
 	if len(base) != CoverSize || len(cur) != CoverSize {
 		log.Fatalf("bad cover table size (%v, %v)", len(base), len(cur))
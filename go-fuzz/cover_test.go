@@ -4,35 +4,75 @@
 package main
 
 import (
+	"math/rand"
 	"testing"
 
 	. "github.com/dvyukov/go-fuzz/go-fuzz-defs"
 )
 
-func BenchmarkCompareCoverBody(b *testing.B) {
+var compareCoverImpls = []struct {
+	name string
+	fn   func(base, cur []byte) bool
+}{
+	{"body", compareCoverBody},
+	{"words", compareCoverWords},
+	{"bytes", compareCoverDump},
+}
+
+func TestCompareCover(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
 	base := make([]byte, CoverSize)
 	cur := make([]byte, CoverSize)
-
-	// Set 1 at both ends, so that it is easy regardless of which end compareCoverBody starts from.
-	cur[0] = 1
-	cur[CoverSize-1] = 1
-	b.Run("easy", func(b *testing.B) {
-		for i := 0; i < b.N; i++ {
-			if !compareCoverBody(base, cur) {
-				b.Fatalf("cur should have increased coverage")
-			}
+	for iter := 0; iter < 1000; iter++ {
+		for i := range base {
+			base[i], cur[i] = 0, 0
+		}
+		for n := r.Intn(10); n > 0; n-- {
+			idx := r.Intn(CoverSize)
+			base[idx] = byte(r.Intn(256))
+			cur[idx] = base[idx]
 		}
-	})
-	cur[0] = 0
-	cur[CoverSize-1] = 0
-
-	// Set 1 in the middle, so that it is hard regardless of which end compareCoverBody starts from.
-	cur[CoverSize/2] = 1
-	b.Run("hard", func(b *testing.B) {
-		for i := 0; i < b.N; i++ {
-			if !compareCoverBody(base, cur) {
-				b.Fatalf("cur should have increased coverage")
+		for n := r.Intn(3); n > 0; n-- {
+			cur[r.Intn(CoverSize)] = byte(r.Intn(256))
+		}
+		want := compareCoverDump(base, cur)
+		for _, impl := range compareCoverImpls {
+			if got := impl.fn(base, cur); got != want {
+				t.Fatalf("%v: got %v, want %v", impl.name, got, want)
 			}
 		}
-	})
+	}
+}
+
+func BenchmarkCompareCoverBody(b *testing.B) {
+	for _, impl := range compareCoverImpls {
+		compare := impl.fn
+		b.Run(impl.name, func(b *testing.B) {
+			base := make([]byte, CoverSize)
+			cur := make([]byte, CoverSize)
+
+			// Set 1 at both ends, so that it is easy regardless of which end compareCoverBody starts from.
+			cur[0] = 1
+			cur[CoverSize-1] = 1
+			b.Run("easy", func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					if !compare(base, cur) {
+						b.Fatalf("cur should have increased coverage")
+					}
+				}
+			})
+			cur[0] = 0
+			cur[CoverSize-1] = 0
+
+			// Set 1 in the middle, so that it is hard regardless of which end compareCoverBody starts from.
+			cur[CoverSize/2] = 1
+			b.Run("hard", func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					if !compare(base, cur) {
+						b.Fatalf("cur should have increased coverage")
+					}
+				}
+			})
+		})
+	}
 }
@@ -0,0 +1,51 @@
+// Copyright 2026 go-fuzz project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os/exec"
+	"runtime"
+	"sync"
+	"syscall"
+)
+
+var (
+	starterOnce sync.Once
+	starterReqs chan starterReq
+)
+
+type starterReq struct {
+	cmd *exec.Cmd
+	res chan error
+}
+
+// startTestee starts cmd and makes the kernel kill it when go-fuzz dies,
+// even if it is killed with SIGKILL or by the OOM killer and has no chance to clean up.
+// The parent death signal is delivered when the thread that forked the child exits,
+// not the whole process. Go runtime may terminate idle threads, so all testees
+// are started from a single goroutine locked to its thread for the process lifetime.
+// The signal only reaches the testee itself; processes it started survive,
+// unless -cgroup limits are used: then the next go-fuzz run kills them
+// together with the stale cgroup (see removeStaleCgroups).
+func startTestee(cmd *exec.Cmd) error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = new(syscall.SysProcAttr)
+	}
+	cmd.SysProcAttr.Pdeathsig = syscall.SIGKILL
+	starterOnce.Do(func() {
+		starterReqs = make(chan starterReq)
+		go testeeStarter()
+	})
+	res := make(chan error)
+	starterReqs <- starterReq{cmd, res}
+	return <-res
+}
+
+func testeeStarter() {
+	// Never unlocked, so the thread lives as long as go-fuzz.
+	runtime.LockOSThread()
+	for req := range starterReqs {
+		req.res <- req.cmd.Start()
+	}
+}
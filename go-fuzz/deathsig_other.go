@@ -0,0 +1,14 @@
+// Copyright 2026 go-fuzz project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+// +build !linux
+
+package main
+
+import (
+	"os/exec"
+)
+
+func startTestee(cmd *exec.Cmd) error {
+	return cmd.Start()
+}
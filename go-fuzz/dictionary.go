@@ -0,0 +1,162 @@
+// Copyright 2026 go-fuzz project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	dictDecay         = 0.999 // weight multiplier applied every sync period
+	dictMinWeight     = 0.1   // literals with lower weight are evicted
+	dictMaxSize       = 1000  // max number of literals used by mutator
+	dictHarvestWeight = 0.25  // weight bump of a token found in a new corpus input
+	dictMinToken      = 3     // tokens of new corpus inputs are harvested if their length
+	dictMaxToken      = 32    // is in [dictMinToken, dictMaxToken]
+)
+
+// Dictionary is a set of literals harvested during fuzzing: sonar operands
+// that led to new coverage when substituted into inputs, and tokens of inputs
+// that gave new coverage. A token gets a smaller bump, it becomes a prominent
+// literal only if it keeps showing up in new inputs.
+// Every literal has a weight that is bumped every time it leads to new coverage
+// and decays over time, so that literals that stopped being useful are evicted.
+// The dictionary is persisted in a file, so it survives restarts.
+type Dictionary struct {
+	file    string
+	weights map[string]float64
+	dirty   bool // literals were added or evicted since the last save
+}
+
+func newDictionary(file string) *Dictionary {
+	d := &Dictionary{
+		file:    file,
+		weights: make(map[string]float64),
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("failed to read dictionary: %v", err)
+		}
+		return d
+	}
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		// Every line is "weight quoted-literal".
+		line := s.Text()
+		idx := strings.IndexByte(line, ' ')
+		if idx == -1 {
+			continue
+		}
+		weight, err1 := strconv.ParseFloat(line[:idx], 64)
+		lit, err2 := strconv.Unquote(line[idx+1:])
+		if err1 != nil || err2 != nil {
+			log.Printf("bad dictionary line: %q", line)
+			continue
+		}
+		d.weights[lit] = weight
+	}
+	return d
+}
+
+// add bumps weight of the literal.
+func (d *Dictionary) add(lit []byte) {
+	d.addWeight(lit, 1)
+}
+
+func (d *Dictionary) addWeight(lit []byte, weight float64) {
+	if _, ok := d.weights[string(lit)]; !ok {
+		d.dirty = true
+	}
+	d.weights[string(lit)] += weight
+}
+
+// harvest adds tokens of an input that gave new coverage.
+func (d *Dictionary) harvest(data []byte) {
+	for _, tok := range harvestTokens(data) {
+		d.addWeight(tok, dictHarvestWeight)
+	}
+}
+
+// harvestTokens returns distinct runs of letters, digits and underscores in data,
+// e.g. keywords, identifiers and numbers in text inputs.
+func harvestTokens(data []byte) [][]byte {
+	var toks [][]byte
+	seen := make(map[string]bool)
+	isTokenByte := func(b byte) bool {
+		return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b == '_'
+	}
+	for i := 0; i < len(data); {
+		if !isTokenByte(data[i]) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(data) && isTokenByte(data[j]) {
+			j++
+		}
+		tok := data[i:j]
+		if len(tok) >= dictMinToken && len(tok) <= dictMaxToken && !seen[string(tok)] {
+			seen[string(tok)] = true
+			toks = append(toks, tok)
+		}
+		i = j
+	}
+	return toks
+}
+
+// decay decreases weights of all literals and evicts the ones that are not useful anymore.
+func (d *Dictionary) decay() {
+	for lit, weight := range d.weights {
+		weight *= dictDecay
+		if weight < dictMinWeight {
+			delete(d.weights, lit)
+			d.dirty = true
+			continue
+		}
+		d.weights[lit] = weight
+	}
+}
+
+// literals returns up to dictMaxSize literals with the highest weights.
+// The order does not depend on map order, the mutator picks literals by index.
+func (d *Dictionary) literals() [][]byte {
+	lits := make([]string, 0, len(d.weights))
+	for lit := range d.weights {
+		lits = append(lits, lit)
+	}
+	sort.Slice(lits, func(i, j int) bool {
+		if wi, wj := d.weights[lits[i]], d.weights[lits[j]]; wi != wj {
+			return wi > wj
+		}
+		return lits[i] < lits[j]
+	})
+	if len(lits) > dictMaxSize {
+		lits = lits[:dictMaxSize]
+	}
+	res := make([][]byte, len(lits))
+	for i, lit := range lits {
+		res[i] = []byte(lit)
+	}
+	return res
+}
+
+func (d *Dictionary) save() {
+	var buf bytes.Buffer
+	for _, lit := range d.literals() {
+		fmt.Fprintf(&buf, "%v %q\n", d.weights[string(lit)], lit)
+	}
+	if err := ioutil.WriteFile(d.file, buf.Bytes(), 0660); err != nil {
+		log.Printf("failed to write dictionary: %v", err)
+	}
+	d.dirty = false
+}
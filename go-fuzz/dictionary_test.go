@@ -0,0 +1,64 @@
+// Copyright 2026 go-fuzz project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDictionary(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-fuzz-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "dictionary")
+	d := newDictionary(file)
+	d.add([]byte("foo"))
+	d.add([]byte("\x00\xff bar\n"))
+	d.add([]byte("\x00\xff bar\n"))
+	if !d.dirty {
+		t.Fatalf("dictionary is not dirty after add")
+	}
+	d.save()
+
+	d = newDictionary(file)
+	lits := d.literals()
+	if len(lits) != 2 || !bytes.Equal(lits[0], []byte("\x00\xff bar\n")) || !bytes.Equal(lits[1], []byte("foo")) {
+		t.Fatalf("bad literals after reload: %q", lits)
+	}
+	for i := 0; i < 10000 && len(d.weights) != 0; i++ {
+		d.decay()
+	}
+	if len(d.weights) != 0 || !d.dirty {
+		t.Fatalf("literals are not evicted by decay: %v", d.weights)
+	}
+
+	// Literals with equal weights are ordered by bytes.
+	d.add([]byte("b"))
+	d.add([]byte("c"))
+	d.add([]byte("a"))
+	d.add([]byte("c"))
+	lits = d.literals()
+	if len(lits) != 3 || string(lits[0]) != "c" || string(lits[1]) != "a" || string(lits[2]) != "b" {
+		t.Fatalf("bad literals order: %q", lits)
+	}
+}
+
+func TestHarvestTokens(t *testing.T) {
+	toks := harvestTokens([]byte("SELECT a, b_2 FROM tbl WHERE x = 20991231 AND tbl.y\x00SELECT"))
+	var got []string
+	for _, tok := range toks {
+		got = append(got, string(tok))
+	}
+	want := []string{"SELECT", "b_2", "FROM", "tbl", "WHERE", "20991231", "AND"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("harvested %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2026 go-fuzz project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"time"
+)
+
+const (
+	discoveryWindow = time.Hour   // discovery rate is estimated over this period
+	discoveryPeriod = time.Minute // how often discovery samples are taken
+)
+
+// discoverySample is the number of corpus inputs found by fuzzing during this run
+// and total testee CPU time at some moment.
+type discoverySample struct {
+	time  time.Time
+	found int
+	cpu   time.Duration
+}
+
+// addDiscoverySample appends a sample to the history and drops samples
+// that are not needed to cover the last discoveryWindow anymore.
+func addDiscoverySample(samples []discoverySample, s discoverySample) []discoverySample {
+	samples = append(samples, s)
+	for len(samples) > 2 && s.time.Sub(samples[1].time) >= discoveryWindow {
+		samples = samples[1:]
+	}
+	return samples
+}
+
+// discoveryRate estimates how many new corpus inputs one CPU-hour of fuzzing
+// finds at this point of the campaign, based on the last discoveryWindow.
+// The rate goes down as fuzzing saturates, it is the expected benefit of
+// continuing the campaign. ok is false if the history is too short.
+func discoveryRate(samples []discoverySample) (rate float64, ok bool) {
+	if len(samples) < 2 {
+		return 0, false
+	}
+	first, last := samples[0], samples[len(samples)-1]
+	if last.time.Sub(first.time) < discoveryWindow || last.cpu <= first.cpu {
+		return 0, false
+	}
+	return float64(last.found-first.found) / (last.cpu - first.cpu).Hours(), true
+}
@@ -0,0 +1,43 @@
+// Copyright 2026 go-fuzz project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiscoveryRate(t *testing.T) {
+	start := time.Now()
+	var samples []discoverySample
+	for i := 0; i <= 120; i++ {
+		// 10 new inputs per minute during the first hour, 1 per minute afterwards,
+		// with 2 testee CPU-minutes per minute.
+		corpus := 10 * i
+		if i > 60 {
+			corpus = 600 + (i - 60)
+		}
+		s := discoverySample{start.Add(time.Duration(i) * time.Minute), corpus, time.Duration(2*i) * time.Minute}
+		samples = addDiscoverySample(samples, s)
+		rate, ok := discoveryRate(samples)
+		if i < 60 {
+			if ok {
+				t.Fatalf("minute %v: got rate %v before the window is full", i, rate)
+			}
+			continue
+		}
+		if !ok {
+			t.Fatalf("minute %v: no rate", i)
+		}
+		if i == 60 && rate != 300 {
+			t.Fatalf("minute %v: got rate %v, want 300", i, rate)
+		}
+		if i == 120 && rate != 30 {
+			t.Fatalf("minute %v: got rate %v, want 30", i, rate)
+		}
+	}
+	if len(samples) > 62 {
+		t.Fatalf("history is not trimmed: %v samples", len(samples))
+	}
+}
@@ -4,10 +4,13 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"log"
 	"net/rpc"
+	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -28,29 +31,52 @@ const (
 	defScore = 10.0
 )
 
+// Values of -schedule.
+const (
+	scheduleDefault = "default" // balance input speed, coverage, depth and user boost
+	scheduleExplore = "explore" // additionally prefer recently discovered inputs
+	scheduleExploit = "exploit" // additionally prefer fast inputs with large coverage
+	scheduleRare    = "rare"    // additionally prefer inputs that hit rarely hit code
+)
+
 // Hub contains data shared between all workers in the process (e.g. corpus).
 // This reduces memory consumption for highly parallel workers.
 // Hub also handles communication with the coordinator.
 type Hub struct {
+	slowestNs uint64 // atomic, Ns of the fastest of slowest once it is full; first for alignment
+
 	id          int
 	coordinator *rpc.Client
+	seed        uint64 // campaign seed received from coordinator
+	triage      bool   // coordinator decides on suppressions, see ConnectRes.Triage
 
 	ro atomic.Value // *ROData
 
 	maxCoverMu sync.Mutex
 	maxCover   atomic.Value // []byte
+	noiseMask  []int        // cover table indices excluded from coverage decisions, set before workers start
 
 	initialTriage uint32
 
 	corpusCoverSize int
 	corpusSigs      map[Sig]struct{}
+	restoredSigs    map[Sig]struct{} // inputs restored from workdir that are not yet in corpus
 	corpusStale     bool
 	triageQueue     []CoordinatorInput
+	deferred        []CoordinatorInput // go to triageQueue after the initial triage, see ConnectRes.Deferred
 
 	triageC     chan CoordinatorInput
 	newInputC   chan Input
 	newCrasherC chan NewCrasherArgs
 	syncC       chan Stats
+	minimizeC   chan struct{} // crasher minimization slots, nil if not limited
+	newLiteralC chan []byte
+	newSlowC    chan SlowInput
+
+	dict *Dictionary
+
+	slowest        []SlowInput // slowest inputs seen by this hub, sorted by decreasing Ns
+	slowestPending []SlowInput // new slowest inputs not yet sent to coordinator
 
 	stats         Stats
 	corpusOrigins [execCount]uint64
@@ -63,6 +89,7 @@ type ROData struct {
 	suppressions map[Sig]struct{}
 	strLits      [][]byte // string literals in testee
 	intLits      [][]byte // int literals in testee
+	dictLits     [][]byte // literals harvested during fuzzing
 	coverBlocks  map[int][]CoverBlock
 	sonarSites   []SonarSite
 	verse        *versifier.Verse
@@ -71,21 +98,49 @@ type ROData struct {
 type Stats struct {
 	execs    uint64
 	restarts uint64
+	execTime [execCount]uint64 // testee execution time in ns, by exec type
+
+	latency   [len(latencyBuckets) + 1]uint64 // histogram of testee-reported execution times
+	latencyNs uint64                          // sum of testee-reported execution times
+}
+
+// execPurpose groups exec types for execution time accounting.
+func execPurpose(typ execType) string {
+	switch typ {
+	case execMinimizeInput, execMinimizeCrasher:
+		return "minimize"
+	case execBootstrap, execCorpus, execTriageInput, execRevive:
+		return "triage"
+	default:
+		return "fuzz"
+	}
 }
 
 func newHub(metadata MetaData) *Hub {
 	procs := *flagProcs
 	hub := &Hub{
-		corpusSigs:  make(map[Sig]struct{}),
-		triageC:     make(chan CoordinatorInput, procs),
-		newInputC:   make(chan Input, procs),
-		newCrasherC: make(chan NewCrasherArgs, procs),
-		syncC:       make(chan Stats, procs),
+		corpusSigs:   make(map[Sig]struct{}),
+		restoredSigs: make(map[Sig]struct{}),
+		triageC:      make(chan CoordinatorInput, procs),
+		newInputC:    make(chan Input, procs),
+		newCrasherC:  make(chan NewCrasherArgs, procs),
+		syncC:        make(chan Stats, procs),
+		newLiteralC:  make(chan []byte, procs),
+		newSlowC:     make(chan SlowInput, procs),
+		dict:         newDictionary(filepath.Join(*flagWorkdir, "dictionary")),
+	}
+	if *flagMinimizeProcs > 0 {
+		hub.minimizeC = make(chan struct{}, *flagMinimizeProcs)
 	}
 
 	if err := hub.connect(); err != nil {
 		log.Fatalf("failed to connect to coordinator: %v", err)
 	}
+	// Inputs saved during previous shutdown go to triage before anything else.
+	recovered := loadRecovery()
+	hub.triageQueue = append(hub.triageQueue, recovered...)
+	hub.noteRestored(recovered)
+	hub.initialTriage += uint32(len(recovered))
 
 	coverBlocks := make(map[int][]CoverBlock)
 	for _, b := range metadata.Blocks {
@@ -116,6 +171,7 @@ func newHub(metadata MetaData) *Hub {
 			ro.intLits = append(ro.intLits, []byte(lit.Val))
 		}
 	}
+	ro.dictLits = hub.dict.literals()
 	hub.ro.Store(ro)
 
 	go hub.loop()
@@ -145,11 +201,43 @@ func (hub *Hub) connect() error {
 
 	hub.coordinator = c
 	hub.id = res.ID
+	hub.seed = res.Seed
+	hub.triage = res.Triage
 	hub.initialTriage = uint32(len(res.Corpus))
 	hub.triageQueue = res.Corpus
+	hub.deferred = append(hub.deferred, res.Deferred...)
+	hub.noteRestored(res.Corpus)
+	hub.noteRestored(res.Deferred)
 	return nil
 }
 
+// noteRestored remembers inputs that were not discovered during this run (see Input.fresh).
+func (hub *Hub) noteRestored(inputs []CoordinatorInput) {
+	for _, input := range inputs {
+		sig := hash(input.Data)
+		if _, ok := hub.corpusSigs[sig]; !ok {
+			hub.restoredSigs[sig] = struct{}{}
+		}
+	}
+}
+
+// loadRecovery reads and removes inputs saved by Worker.saveRecovery.
+func loadRecovery() []CoordinatorInput {
+	dir := filepath.Join(*flagWorkdir, "recovery")
+	if _, err := os.Stat(dir); err != nil {
+		return nil
+	}
+	var inputs []CoordinatorInput
+	for _, a := range newPersistentSet(dir).m {
+		inputs = append(inputs, CoordinatorInput{a.data, 0, execCorpus, false, false})
+	}
+	os.RemoveAll(dir)
+	if len(inputs) != 0 {
+		log.Printf("recovered %v in-flight inputs from previous run", len(inputs))
+	}
+	return inputs
+}
+
 func (hub *Hub) loop() {
 	// Local buffer helps to avoid deadlocks on chan overflows.
 	var triageC chan CoordinatorInput
@@ -157,6 +245,10 @@ func (hub *Hub) loop() {
 
 	syncTicker := time.NewTicker(syncPeriod).C
 	for {
+		if len(hub.deferred) > 0 && atomic.LoadUint32(&hub.initialTriage) == 0 {
+			hub.triageQueue = append(hub.triageQueue, hub.deferred...)
+			hub.deferred = nil
+		}
 		if len(hub.triageQueue) > 0 && triageC == nil {
 			n := len(hub.triageQueue) - 1
 			triageInput = hub.triageQueue[n]
@@ -182,9 +274,18 @@ func (hub *Hub) loop() {
 				Execs:         hub.stats.execs,
 				Restarts:      hub.stats.restarts,
 				CoverFullness: hub.corpusCoverSize,
+				ExecTime:      make(map[string]uint64),
+				Slowest:       hub.slowestPending,
+				Latency:       append([]uint64{}, hub.stats.latency[:]...),
+				LatencyNs:     hub.stats.latencyNs,
+			}
+			for typ, ns := range hub.stats.execTime {
+				if ns != 0 {
+					args.ExecTime[execPurpose(execType(typ))] += ns
+				}
 			}
-			hub.stats.execs = 0
-			hub.stats.restarts = 0
+			hub.stats = Stats{}
+			hub.slowestPending = nil
 			var res SyncRes
 			if err := hub.coordinator.Call("Coordinator.Sync", args, &res); err != nil {
 				log.Printf("sync call failed: %v, reconnection to coordinator", err)
@@ -196,10 +297,32 @@ func (hub *Hub) loop() {
 			if len(res.Inputs) > 0 {
 				hub.triageQueue = append(hub.triageQueue, res.Inputs...)
 			}
+			if len(res.Suppressions) > 0 {
+				ro := hub.ro.Load().(*ROData)
+				ro1 := new(ROData)
+				*ro1 = *ro
+				ro1.suppressions = make(map[Sig]struct{})
+				for k, v := range ro.suppressions {
+					ro1.suppressions[k] = v
+				}
+				for _, supp := range res.Suppressions {
+					ro1.suppressions[hash(supp)] = struct{}{}
+				}
+				hub.ro.Store(ro1)
+			}
 			if hub.corpusStale {
 				hub.updateScores()
 				hub.corpusStale = false
 			}
+			hub.dict.decay()
+			if hub.dict.dirty {
+				ro := hub.ro.Load().(*ROData)
+				ro1 := new(ROData)
+				*ro1 = *ro
+				ro1.dictLits = hub.dict.literals()
+				hub.ro.Store(ro1)
+				hub.dict.save()
+			}
 
 		case triageC <- triageInput:
 			// Send new input to workers for triage.
@@ -213,19 +336,38 @@ func (hub *Hub) loop() {
 				triageInput = CoordinatorInput{}
 			}
 
+		case lit := <-hub.newLiteralC:
+			// Literal that led to new coverage.
+			hub.dict.add(lit)
+
+		case s := <-hub.newSlowC:
+			// Input that is slower than the ones we have seen.
+			hub.addSlow(s)
+
 		case s := <-hub.syncC:
 			// Sync from a worker.
 			hub.stats.execs += s.execs
 			hub.stats.restarts += s.restarts
+			for typ, ns := range s.execTime {
+				hub.stats.execTime[typ] += ns
+			}
+			for i, n := range s.latency {
+				hub.stats.latency[i] += n
+			}
+			hub.stats.latencyNs += s.latencyNs
 
 		case input := <-hub.newInputC:
 			// New interesting input from workers.
 			ro := hub.ro.Load().(*ROData)
-			if !compareCover(ro.corpusCover, input.cover) {
+			if !compareCover(ro.corpusCover, input.cover) && input.typ != execRevive {
 				break
 			}
 			sig := hash(input.data)
 			if _, ok := hub.corpusSigs[sig]; ok {
+				// A revived crasher must be retired on coordinator anyway.
+				if input.typ == execRevive && !hub.sendNewInput(input) {
+					return
+				}
 				break
 			}
 
@@ -234,6 +376,9 @@ func (hub *Hub) loop() {
 				log.Printf("hub received new input [%v]%v mine=%v", len(input.data), hash(input.data), input.mine)
 			}
 			hub.corpusSigs[sig] = struct{}{}
+			_, restored := hub.restoredSigs[sig]
+			delete(hub.restoredSigs, sig)
+			input.fresh = !restored
 			ro1 := new(ROData)
 			*ro1 = *ro
 			// Assign it the default score, but mark corpus for score recalculation.
@@ -251,17 +396,14 @@ func (hub *Hub) loop() {
 			if input.res > 0 || input.typ == execBootstrap {
 				ro1.verse = versifier.BuildVerse(ro.verse, input.data)
 			}
+			if input.fresh {
+				hub.dict.harvest(input.data)
+			}
 			hub.ro.Store(ro1)
 			hub.corpusOrigins[input.typ]++
 
-			if input.mine {
-				if err := hub.coordinator.Call("Coordinator.NewInput", NewInputArgs{hub.id, input.data, uint64(input.depth)}, nil); err != nil {
-					log.Printf("new input call failed: %v, reconnecting to coordinator", err)
-					if err := hub.connect(); err != nil {
-						log.Printf("failed to connect to coordinator: %v, killing worker", err)
-						return
-					}
-				}
+			if input.mine && !hub.sendNewInput(input) {
+				return
 			}
 
 			if *flagDumpCover {
@@ -270,7 +412,10 @@ func (hub *Hub) loop() {
 
 		case crash := <-hub.newCrasherC:
 			// New crasher from workers. Woohoo!
-			if crash.Hanging || !*flagDup {
+			// With -triagecmd the suppression is added only once the coordinator
+			// has a verdict, it comes back with Sync.
+			suppress := !*flagDup && !hub.triage
+			if crash.Hanging || suppress {
 				ro := hub.ro.Load().(*ROData)
 				ro1 := new(ROData)
 				*ro1 = *ro
@@ -281,7 +426,7 @@ func (hub *Hub) loop() {
 					}
 					ro1.badInputs[hash(crash.Data)] = struct{}{}
 				}
-				if !*flagDup {
+				if suppress {
 					ro1.suppressions = make(map[Sig]struct{})
 					for k, v := range ro.suppressions {
 						ro1.suppressions[k] = v
@@ -297,6 +442,90 @@ func (hub *Hub) loop() {
 	}
 }
 
+// sendNewInput sends the input to coordinator, it returns false if the coordinator is gone.
+func (hub *Hub) sendNewInput(input Input) bool {
+	if err := hub.coordinator.Call("Coordinator.NewInput", NewInputArgs{hub.id, input.data, uint64(input.depth), input.typ == execRevive, input.fuzzed()}, nil); err != nil {
+		log.Printf("new input call failed: %v, reconnecting to coordinator", err)
+		if err := hub.connect(); err != nil {
+			log.Printf("failed to connect to coordinator: %v, killing worker", err)
+			return false
+		}
+	}
+	return true
+}
+
+// noteLiteral sends a literal that led to new coverage to the dictionary.
+// The literal is dropped if the hub is busy, these are just hints.
+func (hub *Hub) noteLiteral(lit []byte) {
+	select {
+	case hub.newLiteralC <- makeCopy(lit):
+	default:
+	}
+}
+
+// noteSlow sends an input that took ns to execute to the hub,
+// if it is slower than the slowest inputs collected so far (see -slowest).
+// It is called for every execution, so data is copied only if it is going to be sent.
+func (hub *Hub) noteSlow(data []byte, ns uint64) {
+	if ns <= atomic.LoadUint64(&hub.slowestNs) {
+		return
+	}
+	if len(hub.newSlowC) == cap(hub.newSlowC) {
+		return // the hub is busy, the send below would drop it anyway
+	}
+	select {
+	case hub.newSlowC <- SlowInput{makeCopy(data), ns}:
+	default:
+	}
+}
+
+func (hub *Hub) addSlow(s SlowInput) {
+	for i, s1 := range hub.slowest {
+		if bytes.Equal(s1.Data, s.Data) {
+			if s1.Ns >= s.Ns {
+				return
+			}
+			hub.slowest = append(hub.slowest[:i], hub.slowest[i+1:]...)
+			break
+		}
+	}
+	pos := sort.Search(len(hub.slowest), func(i int) bool { return hub.slowest[i].Ns < s.Ns })
+	if pos >= *flagSlowest {
+		return
+	}
+	hub.slowest = append(hub.slowest, SlowInput{})
+	copy(hub.slowest[pos+1:], hub.slowest[pos:])
+	hub.slowest[pos] = s
+	if len(hub.slowest) > *flagSlowest {
+		hub.slowest = hub.slowest[:*flagSlowest]
+	}
+	if len(hub.slowest) == *flagSlowest {
+		atomic.StoreUint64(&hub.slowestNs, hub.slowest[len(hub.slowest)-1].Ns)
+	}
+	hub.slowestPending = append(hub.slowestPending, s)
+}
+
+// startMinimize tries to acquire a crasher minimization slot (see -minimizeprocs).
+// It returns false if all slots are busy.
+func (hub *Hub) startMinimize() bool {
+	if hub.minimizeC == nil {
+		return true
+	}
+	select {
+	case hub.minimizeC <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// finishMinimize releases a slot acquired with startMinimize.
+func (hub *Hub) finishMinimize() {
+	if hub.minimizeC != nil {
+		<-hub.minimizeC
+	}
+}
+
 // Preliminary cover update to prevent new input thundering herd.
 // This function is synchronous to reduce latency.
 func (hub *Hub) updateMaxCover(cover []byte) bool {
@@ -316,6 +545,117 @@ func (hub *Hub) updateMaxCover(cover []byte) bool {
 	return true
 }
 
+// scoreParams are the corpus-wide values that input scores depend on.
+type scoreParams struct {
+	schedule     string // see -schedule
+	avgExecTime  uint64
+	avgCoverSize uint64
+	fresh        int   // number of inputs discovered during this run, used by scheduleExplore
+	edgeFreq     []int // number of inputs that hit every cover table entry, used by scheduleRare
+}
+
+// inputScore calculates score of the input (phase 1 of updateScores).
+// freshIdx is the discovery order of the input among the fresh ones.
+func inputScore(inp *Input, freshIdx int, p *scoreParams) int {
+	score := defScore
+
+	// Execution time multiplier 0.1-3x.
+	// Fuzzing faster inputs increases efficiency.
+	execMul := 1.0
+	execTime := float64(inp.execTime) / float64(p.avgExecTime)
+	if execTime > 10 {
+		execMul = 0.1
+	} else if execTime > 4 {
+		execMul = 0.25
+	} else if execTime > 2 {
+		execMul = 0.5
+	} else if execTime < 0.25 {
+		execMul = 3
+	} else if execTime < 0.33 {
+		execMul = 2
+	} else if execTime < 0.5 {
+		execMul = 1.5
+	}
+	score *= execMul
+
+	// Coverage size multiplier 0.25-3x.
+	// Inputs with larger coverage are more interesting.
+	coverMul := 1.0
+	coverSize := float64(inp.coverSize) / float64(p.avgCoverSize)
+	if coverSize > 3 {
+		coverMul = 3
+	} else if coverSize > 2 {
+		coverMul = 2
+	} else if coverSize > 1.5 {
+		coverMul = 1.5
+	} else if coverSize < 0.3 {
+		coverMul = 0.25
+	} else if coverSize < 0.5 {
+		coverMul = 0.5
+	} else if coverSize < 0.75 {
+		coverMul = 1 / 1.5
+	}
+	score *= coverMul
+
+	// Input depth multiplier 1-5x.
+	// Deeper inputs have higher chances of digging deeper into code.
+	if inp.depth < 10 {
+		// no boost for you
+	} else if inp.depth < 20 {
+		score *= 2
+	} else if inp.depth < 40 {
+		score *= 3
+	} else if inp.depth < 80 {
+		score *= 4
+	} else {
+		score *= 5
+	}
+
+	// User boost (Fuzz function return value) multiplier 1-2x.
+	// We don't know what it is, but user said so.
+	if inp.res > 0 {
+		// Assuming this is a correct input (e.g. deserialized successfully).
+		score *= 2
+	}
+
+	switch p.schedule {
+	case scheduleExploit:
+		// Fast inputs with large coverage once more, i.e. 0.01-9x.
+		score *= execMul * coverMul
+	case scheduleExplore:
+		// Recency multiplier 1-4x for inputs discovered during this run.
+		// Order of the restored corpus says nothing about age of inputs.
+		if inp.fresh {
+			score *= 1 + 3*float64(freshIdx)/float64(p.fresh)
+		}
+	case scheduleRare:
+		// Rarity multiplier 1-4x.
+		// Inputs that hit edges that few other inputs hit explore less fuzzed code.
+		rarest := -1
+		for _, j := range inp.coverEdges {
+			if f := p.edgeFreq[j]; rarest == -1 || f < rarest {
+				rarest = f
+			}
+		}
+		if rarest == -1 {
+			// no edges, no boost
+		} else if rarest <= 1 {
+			score *= 4
+		} else if rarest <= 2 {
+			score *= 3
+		} else if rarest <= 4 {
+			score *= 2
+		}
+	}
+
+	if score < minScore {
+		score = minScore
+	} else if score > maxScore {
+		score = maxScore
+	}
+	return int(score)
+}
+
 func (hub *Hub) updateScores() {
 	ro := hub.ro.Load().(*ROData)
 	ro1 := new(ROData)
@@ -330,74 +670,34 @@ func (hub *Hub) updateScores() {
 		sumCoverSize += uint64(inp.coverSize)
 	}
 	n := uint64(len(corpus))
-	avgExecTime := sumExecTime / n
-	avgCoverSize := sumCoverSize / n
-
-	// Phase 1: calculate score for each input independently.
-	for i, inp := range corpus {
-		score := defScore
-
-		// Execution time multiplier 0.1-3x.
-		// Fuzzing faster inputs increases efficiency.
-		execTime := float64(inp.execTime) / float64(avgExecTime)
-		if execTime > 10 {
-			score /= 10
-		} else if execTime > 4 {
-			score /= 4
-		} else if execTime > 2 {
-			score /= 2
-		} else if execTime < 0.25 {
-			score *= 3
-		} else if execTime < 0.33 {
-			score *= 2
-		} else if execTime < 0.5 {
-			score *= 1.5
-		}
-
-		// Coverage size multiplier 0.25-3x.
-		// Inputs with larger coverage are more interesting.
-		coverSize := float64(inp.coverSize) / float64(avgCoverSize)
-		if coverSize > 3 {
-			score *= 3
-		} else if coverSize > 2 {
-			score *= 2
-		} else if coverSize > 1.5 {
-			score *= 1.5
-		} else if coverSize < 0.3 {
-			score /= 4
-		} else if coverSize < 0.5 {
-			score /= 2
-		} else if coverSize < 0.75 {
-			score /= 1.5
-		}
-
-		// Input depth multiplier 1-5x.
-		// Deeper inputs have higher chances of digging deeper into code.
-		if inp.depth < 10 {
-			// no boost for you
-		} else if inp.depth < 20 {
-			score *= 2
-		} else if inp.depth < 40 {
-			score *= 3
-		} else if inp.depth < 80 {
-			score *= 4
-		} else {
-			score *= 5
+	params := &scoreParams{
+		schedule:     *flagSchedule,
+		avgExecTime:  sumExecTime / n,
+		avgCoverSize: sumCoverSize / n,
+	}
+	switch *flagSchedule {
+	case scheduleExplore:
+		for _, inp := range corpus {
+			if inp.fresh {
+				params.fresh++
+			}
 		}
-
-		// User boost (Fuzz function return value) multiplier 1-2x.
-		// We don't know what it is, but user said so.
-		if inp.res > 0 {
-			// Assuming this is a correct input (e.g. deserialized successfully).
-			score *= 2
+	case scheduleRare:
+		params.edgeFreq = make([]int, CoverSize)
+		for _, inp := range corpus {
+			for _, j := range inp.coverEdges {
+				params.edgeFreq[j]++
+			}
 		}
+	}
 
-		if score < minScore {
-			score = minScore
-		} else if score > maxScore {
-			score = maxScore
+	// Phase 1: calculate score for each input independently.
+	freshIdx := 0
+	for i := range corpus {
+		corpus[i].score = inputScore(&corpus[i], freshIdx, params)
+		if corpus[i].fresh {
+			freshIdx++
 		}
-		corpus[i].score = int(score)
 	}
 
 	// Phase 2: Choose a minimal set of (favored) inputs that give full coverage.
@@ -0,0 +1,46 @@
+// Copyright 2026 go-fuzz project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestInputScore(t *testing.T) {
+	edgeFreq := make([]int, 8)
+	edgeFreq[1], edgeFreq[2], edgeFreq[3] = 5, 1, 3
+	tests := []struct {
+		name     string
+		schedule string
+		inp      Input
+		freshIdx int
+		want     int
+	}{
+		{"average", scheduleDefault, Input{execTime: 100, coverSize: 10}, 0, 10},
+		{"fast", scheduleDefault, Input{execTime: 20, coverSize: 10}, 0, 30},
+		{"slow deep", scheduleDefault, Input{execTime: 1100, coverSize: 10, depth: 100}, 0, 5},
+		{"boosted large cover", scheduleDefault, Input{execTime: 100, coverSize: 40, res: 1}, 0, 60},
+		{"exploit fast large cover", scheduleExploit, Input{execTime: 20, coverSize: 40}, 0, 810},
+		{"exploit slow small cover", scheduleExploit, Input{execTime: 1100, coverSize: 2}, 0, minScore},
+		{"explore restored", scheduleExplore, Input{execTime: 100, coverSize: 10}, 0, 10},
+		{"explore oldest fresh", scheduleExplore, Input{execTime: 100, coverSize: 10, fresh: true}, 0, 10},
+		{"explore newest fresh", scheduleExplore, Input{execTime: 100, coverSize: 10, fresh: true}, 3, 32},
+		{"rare unique edge", scheduleRare, Input{execTime: 100, coverSize: 10, coverEdges: []uint16{1, 2}}, 0, 40},
+		{"rare common edge", scheduleRare, Input{execTime: 100, coverSize: 10, coverEdges: []uint16{1}}, 0, 10},
+		{"rare uncommon edge", scheduleRare, Input{execTime: 100, coverSize: 10, coverEdges: []uint16{1, 3}}, 0, 20},
+		{"rare no edges", scheduleRare, Input{execTime: 100, coverSize: 10}, 0, 10},
+	}
+	for _, test := range tests {
+		p := &scoreParams{
+			schedule:     test.schedule,
+			avgExecTime:  100,
+			avgCoverSize: 10,
+			fresh:        4,
+			edgeFreq:     edgeFreq,
+		}
+		if got := inputScore(&test.inp, test.freshIdx, p); got != test.want {
+			t.Errorf("%v: score = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
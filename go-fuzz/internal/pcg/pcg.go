@@ -4,7 +4,7 @@
 // Package pcg implements a 32 bit PRNG with a 64 bit period: pcg xsh rr 64 32.
 // See https://www.pcg-random.org/ for more information.
 // This implementation is geared specifically towards go-fuzz's needs:
-// Simple creation and use, no reproducibility, no concurrency safety,
+// Simple creation and use, no reproducibility unless seeded explicitly, no concurrency safety,
 // just the methods go-fuzz needs, optimized for speed.
 package pcg
 
@@ -42,6 +42,18 @@ func New() *Rand {
 	return r
 }
 
+// NewSeeded generates a new Rand that produces a reproducible sequence
+// determined by seed and stream. Rands with the same seed and different
+// streams produce independent sequences.
+func NewSeeded(seed, stream uint64) *Rand {
+	r := new(Rand)
+	r.inc = (stream << 1) | 1
+	r.step()
+	r.state += seed
+	r.step()
+	return r
+}
+
 func (r *Rand) step() {
 	r.state *= multiplier
 	r.state += r.inc
@@ -0,0 +1,83 @@
+// Copyright 2026 go-fuzz project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// jsonLog is the log writer with -logformat=json, nil otherwise.
+var jsonLog *jsonLogWriter
+
+// jsonLogWriter turns every log line into a JSON object (see -logformat=json).
+// Every object has time, run, event and msg keys. Lines logged with log package
+// have "log" event, logEvent adds its own event and fields.
+type jsonLogWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	run string // identifies this go-fuzz process in merged logs
+}
+
+// setupLogFormat configures the standard logger according to -logformat.
+func setupLogFormat() {
+	switch *flagLogFormat {
+	case "text":
+	case "json":
+		var run [8]byte
+		rand.Read(run[:])
+		jsonLog = &jsonLogWriter{w: os.Stderr, run: hex.EncodeToString(run[:])}
+		log.SetFlags(0)
+		log.SetOutput(jsonLog)
+	default:
+		log.Fatalf("bad -logformat: %q", *flagLogFormat)
+	}
+}
+
+// logEvent logs a message that log consumers may want to parse, e.g. periodic stats.
+// With -logformat=json fields are emitted as separate keys, otherwise only the message is logged.
+func logEvent(event string, fields map[string]interface{}, format string, args ...interface{}) {
+	if jsonLog == nil {
+		log.Printf(format, args...)
+		return
+	}
+	if err := jsonLog.writeEntry(event, fields, fmt.Sprintf(format, args...)); err != nil {
+		log.Printf("failed to write log: %v", err)
+	}
+}
+
+// Write is called by log once per log entry, so there is no need to split p into lines.
+func (w *jsonLogWriter) Write(p []byte) (int, error) {
+	if err := w.writeEntry("log", nil, string(bytes.TrimSuffix(p, []byte{'\n'}))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *jsonLogWriter) writeEntry(event string, fields map[string]interface{}, msg string) error {
+	entry := make(map[string]interface{}, len(fields)+4)
+	for k, v := range fields {
+		entry[k] = v
+	}
+	entry["time"] = time.Now()
+	entry["run"] = w.run
+	entry["event"] = event
+	entry["msg"] = msg
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err = w.w.Write(append(data, '\n'))
+	return err
+}
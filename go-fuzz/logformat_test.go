@@ -0,0 +1,55 @@
+// Copyright 2026 go-fuzz project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestJSONLogStats(t *testing.T) {
+	var buf bytes.Buffer
+	jsonLog = &jsonLogWriter{w: &buf, run: "run1"}
+	defer func() { jsonLog = nil }()
+
+	stats := coordinatorStats{
+		Workers:          4,
+		Corpus:           100,
+		Execs:            12345,
+		RestartsDenom:    10000,
+		StartTime:        time.Now().Add(-time.Minute),
+		LastNewInputTime: time.Now(),
+		DiscoveryRate:    -1,
+	}
+	logEvent("stats", stats.logFields(), "%v", stats)
+
+	var entry struct {
+		Run             string
+		Event           string
+		Msg             string
+		Workers         uint64
+		Corpus          uint64
+		Execs           uint64
+		ExecsPerRestart uint64   `json:"execs_per_restart"`
+		UptimeSeconds   float64  `json:"uptime_seconds"`
+		DiscoveryRate   *float64 `json:"discovery_rate"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("failed to decode log line %q: %v", buf.Bytes(), err)
+	}
+	if entry.Run != "run1" || entry.Event != "stats" || entry.Msg == "" {
+		t.Fatalf("bad log entry header: %+v", entry)
+	}
+	if entry.Workers != 4 || entry.Corpus != 100 || entry.Execs != 12345 || entry.ExecsPerRestart != 10000 {
+		t.Fatalf("bad stats fields: %+v", entry)
+	}
+	if entry.UptimeSeconds < 60 {
+		t.Fatalf("uptime_seconds = %v, want at least 60", entry.UptimeSeconds)
+	}
+	if entry.DiscoveryRate != nil {
+		t.Fatalf("unknown discovery rate is logged as %v", *entry.DiscoveryRate)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"runtime/debug"
+	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
@@ -30,6 +31,8 @@ var (
 	flagProcs             = flag.Int("procs", runtime.NumCPU(), "parallelism level")
 	flagTimeout           = flag.Int("timeout", 10, "test timeout, in seconds")
 	flagMinimize          = flag.Duration("minimize", 1*time.Minute, "time limit for input minimization")
+	flagMinimizeCrash     = flag.Duration("minimizecrash", 0, "time limit for crasher minimization (defaults to -minimize)")
+	flagMinimizeProcs     = flag.Int("minimizeprocs", 0, "max number of workers minimizing crashers at the same time (0 means no limit)")
 	flagCoordinator       = flag.String("coordinator", "", "coordinator mode (value is coordinator address)")
 	flagWorker            = flag.String("worker", "", "worker mode (value is coordinator address)")
 	flagConnectionTimeout = flag.Duration("connectiontimeout", 1*time.Minute, "time limit for worker to try to connect coordinator")
@@ -37,38 +40,87 @@ var (
 	flagFunc              = flag.String("func", "", "function to fuzz")
 	flagDumpCover         = flag.Bool("dumpcover", false, "dump coverage profile into workdir")
 	flagDup               = flag.Bool("dup", false, "collect duplicate crashers")
+	flagRevive            = flag.Bool("revive", false, "re-test crashers on start and move the ones that do not crash anymore into corpus")
+	flagConfirm           = flag.Int("confirm", 0, "number of fresh test processes a crasher must reproduce on before it is reported")
+	flagSlowest           = flag.Int("slowest", 0, "number of slowest inputs to keep in workdir/slowest")
+	flagDupMax            = flag.Int("dupmax", 0, "max number of duplicate crashers stored per crash signature with -dup (0 means unlimited)")
 	flagTestOutput        = flag.Bool("testoutput", false, "print test binary output to stdout (for debugging only)")
 	flagCoverCounters     = flag.Bool("covercounters", true, "use coverage hit counters")
 	flagSonar             = flag.Bool("sonar", true, "use sonar hints")
+	flagSonarRate         = flag.Int("sonarrate", 1000, "initial number of fuzzing iterations per sonar run (adapted at runtime)")
+	flagCalibrate         = flag.Int("calibrate", 0, "run empty input this many times on start and ignore coverage that differs between runs")
+	flagSeed              = flag.Uint64("seed", 0, "seed for all random choices made by workers (0 means random, the used seed is logged and saved in summary.json)")
+	flagMemLimit          = flag.Int("memlimit", 0, "memory limit for every test process, in MB (linux only, requires -cgroup)")
+	flagCPUQuota          = flag.Float64("cpuquota", 0, "CPU quota for every test process, in CPUs (linux only, requires -cgroup)")
+	flagCgroup            = flag.String("cgroup", "", "writable cgroup v2 dir to create per test process cgroups in")
+	flagSyncDir           = flag.String("syncdir", "", "dir shared with other go-fuzz instances to exchange corpus inputs through (coordinator mode only)")
+	flagGoTraceback       = flag.String("gotraceback", "1", "GOTRACEBACK setting for test processes (e.g. all to get stacks of all goroutines in crash output)")
+	flagStopRate          = flag.Float64("stoprate", 0, "stop once fewer new inputs than this per testee CPU-hour were found during the last hour (coordinator mode only)")
+	flagTriageCmd         = flag.String("triagecmd", "", "shell command to run on every new crasher with input and output file paths appended, its exit code decides whether the crasher is stored (0), suppressed (1) or dropped (2) (coordinator mode only)")
+	flagSchedule          = flag.String("schedule", scheduleDefault, "corpus scheduling policy: default, explore, exploit or rare")
+	flagLogFormat         = flag.String("logformat", "text", "log format: text or json (one JSON object per line)")
+	flagImport            = flag.String("import", "", "dir with additional inputs (one per file) to triage into corpus on start (coordinator mode only)")
 	flagV                 = flag.Int("v", 0, "verbosity level")
 	flagHTTP              = flag.String("http", "", "HTTP server listen address (coordinator mode only)")
+	flagInjectToken       = flag.String("injecttoken", "", "enables /inject HTTP endpoint protected with this bearer token (coordinator mode only)")
+	flagFailOn            = flag.String("failon", "", "comma-separated finding classes (crash, hang, oom) that make go-fuzz exit with non-zero status (coordinator mode only)")
 
 	shutdown        uint32
 	shutdownC       = make(chan struct{})
+	shutdownReqC    = make(chan os.Signal, 1)
+	shutdownMu      sync.Mutex
 	shutdownCleanup []func()
+	exitStatus      int32
 )
 
 func main() {
 	flag.Parse()
+	setupLogFormat()
 	if *flagCoordinator != "" && *flagWorker != "" {
 		log.Fatalf("both -coordinator and -worker are specified")
 	}
 	if *flagHTTP != "" && *flagWorker != "" {
 		log.Fatalf("both -http and -worker are specified")
 	}
+	if *flagInjectToken != "" && *flagHTTP == "" {
+		log.Fatalf("-injecttoken requires -http")
+	}
+	if *flagConfirm < 0 {
+		log.Fatalf("-confirm must not be negative")
+	}
+	if *flagSlowest < 0 {
+		log.Fatalf("-slowest must not be negative")
+	}
+	if *flagMemLimit < 0 || *flagCPUQuota < 0 {
+		log.Fatalf("-memlimit and -cpuquota must not be negative")
+	}
+	if *flagStopRate < 0 {
+		log.Fatalf("-stoprate must not be negative")
+	}
+	switch *flagSchedule {
+	case scheduleDefault, scheduleExplore, scheduleExploit, scheduleRare:
+	default:
+		log.Fatalf("bad -schedule: %q", *flagSchedule)
+	}
+	if *flagSonarRate <= 0 {
+		log.Fatalf("-sonarrate must be positive")
+	}
+	if _, err := parseFailOn(*flagFailOn); err != nil {
+		log.Fatalf("bad -failon: %v", err)
+	}
 
 	go func() {
-		c := make(chan os.Signal, 1)
-		signal.Notify(c, syscall.SIGINT)
-		<-c
+		signal.Notify(shutdownReqC, syscall.SIGINT, syscall.SIGTERM)
+		<-shutdownReqC
 		atomic.StoreUint32(&shutdown, 1)
 		close(shutdownC)
 		log.Printf("shutting down...")
 		time.Sleep(2 * time.Second)
+		shutdownMu.Lock()
 		for _, f := range shutdownCleanup {
 			f()
 		}
-		os.Exit(0)
+		os.Exit(int(atomic.LoadInt32(&exitStatus)))
 	}()
 
 	runtime.GOMAXPROCS(min(*flagProcs, runtime.NumCPU()))
@@ -77,6 +129,8 @@ func main() {
 
 	*flagWorkdir = expandHomeDir(*flagWorkdir)
 	*flagBin = expandHomeDir(*flagBin)
+	*flagSyncDir = expandHomeDir(*flagSyncDir)
+	*flagImport = expandHomeDir(*flagImport)
 
 	if *flagCoordinator != "" || *flagWorker == "" {
 		if *flagWorkdir == "" {
@@ -123,6 +177,21 @@ func main() {
 	select {}
 }
 
+// requestShutdown stops go-fuzz the same way as SIGINT does.
+func requestShutdown() {
+	select {
+	case shutdownReqC <- os.Interrupt:
+	default:
+	}
+}
+
+// addShutdownCleanup registers f to be called before the process exits on SIGINT/SIGTERM.
+func addShutdownCleanup(f func()) {
+	shutdownMu.Lock()
+	defer shutdownMu.Unlock()
+	shutdownCleanup = append(shutdownCleanup, f)
+}
+
 // expandHomeDir expands the tilde sign and replaces it
 // with current users home directory and returns it.
 func expandHomeDir(path string) string {
@@ -0,0 +1,92 @@
+// Copyright 2026 go-fuzz project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// latencyBuckets are upper bounds of the testee execution time histogram.
+var latencyBuckets = [...]time.Duration{
+	10 * time.Microsecond,
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+}
+
+// latencyBucket returns index of the histogram bucket for execution that took ns,
+// len(latencyBuckets) is the +Inf bucket.
+func latencyBucket(ns uint64) int {
+	for i, b := range latencyBuckets {
+		if time.Duration(ns) <= b {
+			return i
+		}
+	}
+	return len(latencyBuckets)
+}
+
+// metrics serves coordinator statistics in Prometheus text format.
+func (c *Coordinator) metrics(w http.ResponseWriter, r *http.Request) {
+	stats := c.coordinatorStats()
+	var buf bytes.Buffer
+	metric := func(name, typ, help string) {
+		fmt.Fprintf(&buf, "# HELP %v %v\n# TYPE %v %v\n", name, help, name, typ)
+	}
+	metric("gofuzz_workers", "gauge", "Number of fuzzing workers.")
+	fmt.Fprintf(&buf, "gofuzz_workers %v\n", stats.Workers)
+	metric("gofuzz_corpus_inputs", "gauge", "Number of inputs in corpus.")
+	fmt.Fprintf(&buf, "gofuzz_corpus_inputs %v\n", stats.Corpus)
+	metric("gofuzz_crashers", "gauge", "Number of stored crashers.")
+	fmt.Fprintf(&buf, "gofuzz_crashers %v\n", stats.Crashers)
+	metric("gofuzz_cover", "gauge", "Number of covered cover table entries.")
+	fmt.Fprintf(&buf, "gofuzz_cover %v\n", stats.Cover)
+	metric("gofuzz_uptime_seconds", "gauge", "Time since coordinator start.")
+	fmt.Fprintf(&buf, "gofuzz_uptime_seconds %v\n", time.Since(stats.StartTime).Seconds())
+	metric("gofuzz_last_new_input_timestamp_seconds", "gauge", "Time when the last new input was added to corpus.")
+	fmt.Fprintf(&buf, "gofuzz_last_new_input_timestamp_seconds %v\n", stats.LastNewInputTime.Unix())
+	if stats.DiscoveryRate >= 0 {
+		metric("gofuzz_discovery_rate", "gauge", "New inputs per testee CPU-hour during the last hour.")
+		fmt.Fprintf(&buf, "gofuzz_discovery_rate %v\n", stats.DiscoveryRate)
+	}
+
+	c.mu.Lock()
+	metric("gofuzz_execs_total", "counter", "Number of testee executions.")
+	fmt.Fprintf(&buf, "gofuzz_execs_total %v\n", c.statExecs)
+	metric("gofuzz_restarts_total", "counter", "Number of testee process restarts.")
+	fmt.Fprintf(&buf, "gofuzz_restarts_total %v\n", c.statRestarts)
+	metric("gofuzz_findings_total", "counter", "Number of new findings during this run, by class.")
+	for _, class := range []string{findingCrash, findingHang, findingOOM} {
+		fmt.Fprintf(&buf, "gofuzz_findings_total{class=%q} %v\n", class, c.findings[class])
+	}
+	metric("gofuzz_exec_time_seconds_total", "counter", "Testee execution time, by purpose.")
+	var purposes []string
+	for purpose := range c.statExecTime {
+		purposes = append(purposes, purpose)
+	}
+	sort.Strings(purposes)
+	for _, purpose := range purposes {
+		fmt.Fprintf(&buf, "gofuzz_exec_time_seconds_total{purpose=%q} %v\n", purpose, time.Duration(c.statExecTime[purpose]).Seconds())
+	}
+	metric("gofuzz_exec_latency_seconds", "histogram", "Execution time of inputs as reported by testees.")
+	total := uint64(0)
+	for i, b := range latencyBuckets {
+		total += c.statLatency[i]
+		fmt.Fprintf(&buf, "gofuzz_exec_latency_seconds_bucket{le=\"%v\"} %v\n", b.Seconds(), total)
+	}
+	total += c.statLatency[len(latencyBuckets)]
+	fmt.Fprintf(&buf, "gofuzz_exec_latency_seconds_bucket{le=\"+Inf\"} %v\n", total)
+	fmt.Fprintf(&buf, "gofuzz_exec_latency_seconds_sum %v\n", time.Duration(c.statLatencyNs).Seconds())
+	fmt.Fprintf(&buf, "gofuzz_exec_latency_seconds_count %v\n", total)
+	c.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write(buf.Bytes())
+}
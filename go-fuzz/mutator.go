@@ -16,8 +16,9 @@ type Mutator struct {
 	r *pcg.Rand
 }
 
-func newMutator() *Mutator {
-	return &Mutator{r: pcg.New()}
+// newMutator creates a mutator with a random stream derived from the campaign seed (see -seed).
+func newMutator(seed, stream uint64) *Mutator {
+	return &Mutator{r: pcg.NewSeeded(seed, stream)}
 }
 
 func (m *Mutator) rand(n int) int {
@@ -346,19 +347,11 @@ func (m *Mutator) mutate(data []byte, ro *ROData) []byte {
 		case 18:
 			// Insert a literal.
 			// TODO: encode int literals in big-endian, base-128, etc.
-			if len(ro.intLits) == 0 && len(ro.strLits) == 0 {
+			lit := m.chooseLiteral(ro)
+			if lit == nil {
 				iter--
 				continue
 			}
-			var lit []byte
-			if len(ro.strLits) != 0 && m.r.Bool() {
-				lit = []byte(ro.strLits[m.rand(len(ro.strLits))])
-			} else {
-				lit = ro.intLits[m.rand(len(ro.intLits))]
-				if m.rand(3) == 0 {
-					lit = reverse(lit)
-				}
-			}
 			pos := m.rand(len(res) + 1)
 			for i := 0; i < len(lit); i++ {
 				res = append(res, 0)
@@ -367,20 +360,8 @@ func (m *Mutator) mutate(data []byte, ro *ROData) []byte {
 			copy(res[pos:], lit)
 		case 19:
 			// Replace with literal.
-			if len(ro.intLits) == 0 && len(ro.strLits) == 0 {
-				iter--
-				continue
-			}
-			var lit []byte
-			if len(ro.strLits) != 0 && m.r.Bool() {
-				lit = []byte(ro.strLits[m.rand(len(ro.strLits))])
-			} else {
-				lit = ro.intLits[m.rand(len(ro.intLits))]
-				if m.rand(3) == 0 {
-					lit = reverse(lit)
-				}
-			}
-			if len(lit) >= len(res) {
+			lit := m.chooseLiteral(ro)
+			if lit == nil || len(lit) >= len(res) {
 				iter--
 				continue
 			}
@@ -394,6 +375,26 @@ func (m *Mutator) mutate(data []byte, ro *ROData) []byte {
 	return res
 }
 
+// chooseLiteral chooses a literal from testee source or from the dictionary
+// of literals harvested during fuzzing. It returns nil if there are no literals.
+// The result must not be modified.
+func (m *Mutator) chooseLiteral(ro *ROData) []byte {
+	if len(ro.dictLits) != 0 && (len(ro.intLits) == 0 && len(ro.strLits) == 0 || m.rand(3) == 0) {
+		return ro.dictLits[m.rand(len(ro.dictLits))]
+	}
+	if len(ro.strLits) != 0 && (len(ro.intLits) == 0 || m.r.Bool()) {
+		return ro.strLits[m.rand(len(ro.strLits))]
+	}
+	if len(ro.intLits) != 0 {
+		lit := ro.intLits[m.rand(len(ro.intLits))]
+		if m.rand(3) == 0 {
+			lit = reverse(lit)
+		}
+		return lit
+	}
+	return nil
+}
+
 // chooseLen chooses length of range mutation.
 // It gives preference to shorter ranges.
 func (m *Mutator) chooseLen(n int) int {
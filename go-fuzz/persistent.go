@@ -106,11 +106,37 @@ func (ps *PersistentSet) add(a Artifact) bool {
 	return true
 }
 
+// remove deletes a blob and its description files.
+// Files created by user are only forgotten, since their names are unknown.
+func (ps *PersistentSet) remove(sig Sig) {
+	a, ok := ps.m[sig]
+	if !ok {
+		return
+	}
+	delete(ps.m, sig)
+	if a.user {
+		return
+	}
+	os.Remove(persistentFilename(ps.dir, a, sig))
+	descs, _ := filepath.Glob(descriptionFilename(ps.dir, sig, "*"))
+	for _, f := range descs {
+		os.Remove(f)
+	}
+}
+
+func descriptionFilename(dir string, sig Sig, typ string) string {
+	return filepath.Join(dir, fmt.Sprintf("%v.%v", hex.EncodeToString(sig[:]), typ))
+}
+
 // addDescription creates a complementary to data file on disk.
 func (ps *PersistentSet) addDescription(data []byte, desc []byte, typ string) {
-	sig := hash(data)
-	fname := filepath.Join(ps.dir, fmt.Sprintf("%v.%v", hex.EncodeToString(sig[:]), typ))
+	fname := descriptionFilename(ps.dir, hash(data), typ)
 	if err := ioutil.WriteFile(fname, desc, 0660); err != nil {
 		log.Printf("failed to write file: %v", err)
 	}
 }
+
+// description reads a complementary file previously created with addDescription.
+func (ps *PersistentSet) description(sig Sig, typ string) ([]byte, error) {
+	return ioutil.ReadFile(descriptionFilename(ps.dir, sig, typ))
+}
@@ -144,7 +144,11 @@ func (w *Worker) processSonarData(data, sonar []byte, depth int, smash bool) {
 				if len(tmp) > CoverSize {
 					tmp = tmp[:CoverSize]
 				}
+				queued := len(w.triageQueue)
 				testInput(tmp)
+				if len(w.triageQueue) > queued && len(v2) > 1 {
+					w.hub.noteLiteral(v2)
+				}
 				if flags&SonarString != 0 && len(v1) != len(v2) && len(tmp) < CoverSize {
 					// Update length field.
 					// TODO: handle multi-byte/big-endian/base-128 length fields.
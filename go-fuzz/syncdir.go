@@ -0,0 +1,95 @@
+// Copyright 2026 go-fuzz project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	. "github.com/dvyukov/go-fuzz/go-fuzz-defs"
+)
+
+// syncDirPeriod is how often the coordinator looks for new inputs in -syncdir.
+const syncDirPeriod = 20 * syncPeriod
+
+// exportSyncDir writes a new corpus input into -syncdir, so that other go-fuzz instances can pick it up.
+func exportSyncDir(data []byte) {
+	sig := hash(data)
+	fname := filepath.Join(*flagSyncDir, hex.EncodeToString(sig[:]))
+	if _, err := os.Stat(fname); err == nil {
+		return
+	}
+	// Write to a temp file first, so that other instances never see partially written inputs.
+	f, err := ioutil.TempFile(*flagSyncDir, ".tmp-")
+	if err != nil {
+		log.Printf("failed to create file in sync dir: %v", err)
+		return
+	}
+	_, err = f.Write(data)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), fname)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		log.Printf("failed to write file in sync dir: %v", err)
+	}
+}
+
+// importSyncDir queues inputs that other go-fuzz instances wrote into -syncdir for triage.
+// seen holds names of files that were already processed.
+func (c *Coordinator) importSyncDir(seen map[string]bool) {
+	files, err := ioutil.ReadDir(*flagSyncDir)
+	if err != nil {
+		log.Printf("failed to read sync dir: %v", err)
+		return
+	}
+	var inputs [][]byte
+	var names []string
+	for _, f := range files {
+		name := f.Name()
+		if seen[name] || f.IsDir() || name[0] == '.' {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(*flagSyncDir, name))
+		if err != nil || len(data) > MaxInputSize {
+			seen[name] = true
+			continue
+		}
+		inputs = append(inputs, data)
+		names = append(names, name)
+	}
+	if len(inputs) == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.workers) == 0 {
+		return // retry when workers connect
+	}
+	var workers []*CoordinatorWorker
+	for _, w := range c.workers {
+		workers = append(workers, w)
+	}
+	n := 0
+	for i, data := range inputs {
+		seen[names[i]] = true
+		if _, ok := c.corpus.m[hash(data)]; ok {
+			continue
+		}
+		// Inputs that give new coverage are added to corpus and broadcast to other workers by NewInput.
+		w := workers[n%len(workers)]
+		w.pending = append(w.pending, CoordinatorInput{data, 0, execCorpus, false, false})
+		n++
+	}
+	if n != 0 {
+		log.Printf("imported %v inputs from sync dir", n)
+	}
+}
@@ -6,10 +6,12 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"os/exec"
 	"syscall"
+	"time"
 )
 
 func lowerProcessPrio() {
@@ -41,3 +43,42 @@ func setupCommMapping(cmd *exec.Cmd, comm *Mapping, rOut, wIn *os.File) {
 	cmd.ExtraFiles = append(cmd.ExtraFiles, rOut)
 	cmd.ExtraFiles = append(cmd.ExtraFiles, wIn)
 }
+
+// setupProcessGroup makes the testee a leader of a new process group,
+// so that it can be killed together with processes it spawns. Processes that
+// leave the group with setsid or setpgid are not covered, only the per-testee
+// cgroup (see -memlimit and -cpuquota) catches them.
+func setupProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = new(syscall.SysProcAttr)
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// signalProcessGroup sends sig to the testee and all processes in its group.
+func signalProcessGroup(p *os.Process, sig syscall.Signal) {
+	if err := syscall.Kill(-p.Pid, sig); err != nil {
+		p.Signal(sig)
+	}
+}
+
+// killProcessGroup kills remaining processes in the testee group after the testee has exited
+// and reports whether the group is gone (see setupProcessGroup for processes that are not seen).
+// Killed processes usually disappear within milliseconds, so it polls with growing delays.
+func killProcessGroup(p *os.Process) bool {
+	delay := time.Millisecond
+	for i := 0; i < 10; i++ {
+		if syscall.Kill(-p.Pid, syscall.SIGKILL) == syscall.ESRCH {
+			return true
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+	return false
+}
+
+// shellCommand runs script with sh, args are available in the script as $1, $2, ...
+// and are also appended to the script itself, so that "cmd --flag" gets them as its last arguments.
+func shellCommand(ctx context.Context, script string, args ...string) *exec.Cmd {
+	return exec.CommandContext(ctx, "sh", append([]string{"-c", script + ` "$@"`, "sh"}, args...)...)
+}
@@ -4,6 +4,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -56,3 +57,20 @@ func setupCommMapping(cmd *exec.Cmd, comm *Mapping, rOut, wIn *os.File) {
 	cmd.Env = append(cmd.Env, fmt.Sprintf("GO_FUZZ_IN_FD=%v", rOut.Fd()))
 	cmd.Env = append(cmd.Env, fmt.Sprintf("GO_FUZZ_OUT_FD=%v", wIn.Fd()))
 }
+
+func setupProcessGroup(cmd *exec.Cmd) {
+	// TODO: use job objects to kill processes spawned by the testee.
+}
+
+func signalProcessGroup(p *os.Process, sig syscall.Signal) {
+	p.Signal(sig)
+}
+
+func killProcessGroup(p *os.Process) bool {
+	return true
+}
+
+// shellCommand runs script with cmd.exe, args are appended to the script.
+func shellCommand(ctx context.Context, script string, args ...string) *exec.Cmd {
+	return exec.CommandContext(ctx, "cmd", append([]string{"/C", script}, args...)...)
+}
@@ -32,17 +32,18 @@ type Testee struct {
 	writebuf    [9]byte  // reusable write buffer
 	resbuf      [24]byte // reusable results buffer
 	startTime   int64
+	timeout     int64 // timeout of the current execution in ns
 	execs       int
 	outputC     chan []byte
 	downC       chan bool
 	down        bool
 	fnidx       uint8
+	cgroup      *testeeCgroup // nil if there are no resource limits
 }
 
 // TestBinary handles communication with and restring of testee subprocesses.
 type TestBinary struct {
 	fileName      string
-	commFile      string
 	comm          *Mapping
 	periodicCheck func()
 
@@ -52,6 +53,7 @@ type TestBinary struct {
 
 	testee       *Testee
 	testeeBuffer []byte // reusable buffer for collecting testee output
+	traceback    string // overrides -gotraceback for new testees if not empty
 
 	stats *Stats
 
@@ -59,8 +61,8 @@ type TestBinary struct {
 }
 
 func init() {
-	if unsafe.Offsetof(Testee{}.startTime)%8 != 0 {
-		println(unsafe.Offsetof(Testee{}.startTime))
+	if unsafe.Offsetof(Testee{}.startTime)%8 != 0 || unsafe.Offsetof(Testee{}.timeout)%8 != 0 {
+		println(unsafe.Offsetof(Testee{}.startTime), unsafe.Offsetof(Testee{}.timeout))
 		panic("bad atomic field offset")
 	}
 }
@@ -69,6 +71,31 @@ func init() {
 // before we start to overwrite old output.
 const testeeBufferSize = 1 << 20
 
+// testeeEnv returns environment variables that are set for testees on top of the go-fuzz environment.
+// traceback overrides -gotraceback if not empty.
+func testeeEnv(traceback string) []string {
+	if traceback == "" {
+		traceback = *flagGoTraceback
+	}
+	return []string{"GOTRACEBACK=" + traceback}
+}
+
+// oomHeader starts output of testees killed for exceeding -memlimit.
+const oomHeader = "program exceeded memory limit"
+
+// hangGracePeriod is how long a testee aborted because of a hang has to dump goroutine stacks before it is killed.
+const hangGracePeriod = 10 * time.Second
+
+// hangTraceback returns GOTRACEBACK value for replaying hangs:
+// stacks of all goroutines are required to see where the program is stuck.
+func hangTraceback() string {
+	switch *flagGoTraceback {
+	case "2", "system", "crash":
+		return *flagGoTraceback
+	}
+	return "all"
+}
+
 func newTestBinary(fileName string, periodicCheck func(), stats *Stats, fnidx uint8) *TestBinary {
 	comm, err := ioutil.TempFile("", "go-fuzz-comm")
 	if err != nil {
@@ -77,9 +104,11 @@ func newTestBinary(fileName string, periodicCheck func(), stats *Stats, fnidx ui
 	comm.Truncate(CoverSize + MaxInputSize + SonarRegionSize)
 	comm.Close()
 	mapping, mem := createMapping(comm.Name(), CoverSize+MaxInputSize+SonarRegionSize)
+	// The mapping does not need the file name anymore, remove the file right away
+	// so that it does not leak if go-fuzz is killed.
+	os.Remove(comm.Name())
 	return &TestBinary{
 		fileName:      fileName,
-		commFile:      comm.Name(),
 		comm:          mapping,
 		periodicCheck: periodicCheck,
 		coverRegion:   mem[:CoverSize],
@@ -97,10 +126,33 @@ func (bin *TestBinary) close() {
 		bin.testee = nil
 	}
 	bin.comm.destroy()
-	os.Remove(bin.commFile)
+}
+
+// testHang replays a hanging input on a fresh testee that dumps stacks of all goroutines when it is aborted.
+func (bin *TestBinary) testHang(data []byte, timeout time.Duration) (res int, ns uint64, cover, sonar, output []byte, crashed, hanged bool) {
+	bin.restart()
+	bin.traceback = hangTraceback()
+	defer func() {
+		bin.restart()
+		bin.traceback = ""
+	}()
+	return bin.testTimeout(data, timeout)
+}
+
+// restart kills the current testee process, so that the next test starts a fresh one.
+func (bin *TestBinary) restart() {
+	if bin.testee != nil {
+		bin.testee.shutdown()
+		bin.testee = nil
+	}
 }
 
 func (bin *TestBinary) test(data []byte) (res int, ns uint64, cover, sonar, output []byte, crashed, hanged bool) {
+	return bin.testTimeout(data, time.Duration(*flagTimeout)*time.Second)
+}
+
+// testTimeout is the same as test, but uses the given timeout instead of -timeout.
+func (bin *TestBinary) testTimeout(data []byte, timeout time.Duration) (res int, ns uint64, cover, sonar, output []byte, crashed, hanged bool) {
 	if len(data) > MaxInputSize {
 		panic("input is too large")
 	}
@@ -112,10 +164,10 @@ func (bin *TestBinary) test(data []byte) (res int, ns uint64, cover, sonar, outp
 		bin.stats.execs++
 		if bin.testee == nil {
 			bin.stats.restarts++
-			bin.testee = newTestee(bin.fileName, bin.comm, bin.coverRegion, bin.inputRegion, bin.sonarRegion, bin.fnidx, bin.testeeBuffer)
+			bin.testee = newTestee(bin.fileName, bin.comm, bin.coverRegion, bin.inputRegion, bin.sonarRegion, bin.fnidx, bin.testeeBuffer, bin.traceback)
 		}
 		var retry bool
-		res, ns, cover, sonar, crashed, hanged, retry = bin.testee.test(data)
+		res, ns, cover, sonar, crashed, hanged, retry = bin.testee.test(data, timeout)
 		if retry {
 			bin.testee.shutdown()
 			bin.testee = nil
@@ -124,7 +176,7 @@ func (bin *TestBinary) test(data []byte) (res int, ns uint64, cover, sonar, outp
 		if crashed {
 			output = bin.testee.shutdown()
 			if hanged {
-				hdr := fmt.Sprintf("program hanged (timeout %v seconds)\n\n", *flagTimeout)
+				hdr := fmt.Sprintf("program hanged (timeout %v seconds)\n\n", timeout.Seconds())
 				output = append([]byte(hdr), output...)
 			}
 			bin.testee = nil
@@ -134,7 +186,7 @@ func (bin *TestBinary) test(data []byte) (res int, ns uint64, cover, sonar, outp
 	}
 }
 
-func newTestee(bin string, comm *Mapping, coverRegion, inputRegion, sonarRegion []byte, fnidx uint8, buffer []byte) *Testee {
+func newTestee(bin string, comm *Mapping, coverRegion, inputRegion, sonarRegion []byte, fnidx uint8, buffer []byte, traceback string) *Testee {
 retry:
 	rIn, wIn, err := os.Pipe()
 	if err != nil {
@@ -158,11 +210,16 @@ retry:
 		cmd.Stderr = wStdout
 	}
 	cmd.Env = append([]string{}, os.Environ()...)
-	cmd.Env = append(cmd.Env, "GOTRACEBACK=1")
+	cmd.Env = append(cmd.Env, testeeEnv(traceback)...)
 	setupCommMapping(cmd, comm, rOut, wIn)
-	if err = cmd.Start(); err != nil {
+	setupProcessGroup(cmd)
+	cgroup := newTesteeCgroup()
+	cgroup.prepare(cmd)
+	if err = startTestee(cmd); err != nil {
 		// This can be a transient failure like "cannot allocate memory" or "text file is busy".
-		log.Printf("failed to start test binary: %v", err)
+		logEvent("testee_start_failed", map[string]interface{}{"error": err.Error()}, "failed to start test binary: %v", err)
+		cgroup.startFailed(err)
+		cgroup.destroy()
 		rIn.Close()
 		wIn.Close()
 		rOut.Close()
@@ -175,6 +232,7 @@ retry:
 	rOut.Close()
 	wIn.Close()
 	wStdout.Close()
+	cgroup.add(cmd.Process.Pid)
 	t := &Testee{
 		coverRegion: coverRegion,
 		inputRegion: inputRegion,
@@ -186,6 +244,7 @@ retry:
 		outputC:     make(chan []byte),
 		downC:       make(chan bool),
 		fnidx:       fnidx,
+		cgroup:      cgroup,
 	}
 	// Stdout reader goroutine.
 	go func() {
@@ -198,9 +257,13 @@ retry:
 		data := buffer
 		filled := 0
 		for {
-			select {
-			case <-ticker.C:
-			case <-t.downC:
+			// Once the testee is aborted because of a hang, read continuously,
+			// otherwise a large stack dump fills up the pipe and blocks the testee.
+			if atomic.LoadInt64(&t.startTime) != -1 {
+				select {
+				case <-ticker.C:
+				case <-t.downC:
+				}
 			}
 			n, err := t.stdoutPipe.Read(data[filled:])
 			if *flagV >= 3 {
@@ -222,18 +285,21 @@ retry:
 	}()
 	// Hang watcher goroutine.
 	go func() {
-		timeout := time.Duration(*flagTimeout) * time.Second
-		ticker := time.NewTicker(timeout / 2)
+		ticker := time.NewTicker(time.Duration(*flagTimeout) * time.Second / 2)
 		for {
 			select {
 			case <-ticker.C:
 				start := atomic.LoadInt64(&t.startTime)
-				if start != 0 && time.Now().UnixNano()-start > int64(timeout) {
+				if start != 0 && time.Now().UnixNano()-start > atomic.LoadInt64(&t.timeout) {
 					atomic.StoreInt64(&t.startTime, -1)
-					t.cmd.Process.Signal(syscall.SIGABRT)
-					time.Sleep(time.Second)
-					t.cmd.Process.Signal(syscall.SIGKILL)
+					signalProcessGroup(t.cmd.Process, syscall.SIGABRT)
 					ticker.Stop()
+					// Give the testee time to finish the stack dump and exit by itself.
+					select {
+					case <-time.After(hangGracePeriod):
+						signalProcessGroup(t.cmd.Process, syscall.SIGKILL)
+					case <-t.downC:
+					}
 					return
 				}
 			case <-t.downC:
@@ -248,14 +314,14 @@ retry:
 		select {
 		case <-t.downC:
 		case <-shutdownC:
-			t.cmd.Process.Signal(syscall.SIGKILL)
+			signalProcessGroup(t.cmd.Process, syscall.SIGKILL)
 		}
 	}()
 	return t
 }
 
 // test passes data for testing.
-func (t *Testee) test(data []byte) (res int, ns uint64, cover, sonar []byte, crashed, hanged, retry bool) {
+func (t *Testee) test(data []byte, timeout time.Duration) (res int, ns uint64, cover, sonar []byte, crashed, hanged, retry bool) {
 	if t.down {
 		log.Fatalf("cannot test: testee is already shutdown")
 	}
@@ -264,12 +330,13 @@ func (t *Testee) test(data []byte) (res int, ns uint64, cover, sonar []byte, cra
 	// so we recreate it periodically.
 	t.execs++
 	if t.execs > 10000 {
-		t.cmd.Process.Signal(syscall.SIGKILL)
+		signalProcessGroup(t.cmd.Process, syscall.SIGKILL)
 		retry = true
 		return
 	}
 
 	copy(t.inputRegion[:], data)
+	atomic.StoreInt64(&t.timeout, int64(timeout))
 	atomic.StoreInt64(&t.startTime, time.Now().UnixNano())
 	t.writebuf[0] = t.fnidx
 	binary.LittleEndian.PutUint64(t.writebuf[1:], uint64(len(data)))
@@ -312,12 +379,21 @@ func (t *Testee) shutdown() (output []byte) {
 		log.Fatalf("cannot shutdown: testee is already shutdown")
 	}
 	t.down = true
-	t.cmd.Process.Kill() // it is probably already dead, but kill it again to be sure
-	close(t.downC)       // wakeup stdout reader
+	// It is probably already dead, but kill it again to be sure.
+	signalProcessGroup(t.cmd.Process, syscall.SIGKILL)
+	close(t.downC) // wakeup stdout reader
 	out := <-t.outputC
 	if err := t.cmd.Wait(); err != nil {
 		out = append(out, err.Error()...)
 	}
+	if !killProcessGroup(t.cmd.Process) {
+		log.Printf("processes in group of testee %v are still alive after kill", t.cmd.Process.Pid)
+	}
+	if t.cgroup.oomKilled() {
+		hdr := fmt.Sprintf("%v (%v MB)\n\n", oomHeader, *flagMemLimit)
+		out = append([]byte(hdr), out...)
+	}
+	t.cgroup.destroy()
 	t.inPipe.Close()
 	t.outPipe.Close()
 	t.stdoutPipe.Close()
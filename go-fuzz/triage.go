@@ -0,0 +1,70 @@
+// Copyright 2026 go-fuzz project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// Exit codes of -triagecmd.
+const (
+	triageStore    = 0 // new bug, store the crasher
+	triageSuppress = 1 // known bug, drop the crasher and all future crashers with the same stack
+	triageDrop     = 2 // duplicate of an already stored crasher, drop only this one
+)
+
+const (
+	triageTimeout  = time.Minute
+	triageMaxProcs = 4 // new crashers are dropped while this many commands are running
+)
+
+// runTriageCmd runs -triagecmd on a new crasher and returns its verdict.
+// The command is run by the shell, so it can use quoting, pipes and so on.
+// It gets paths to files with the input and the crash output as the last
+// two arguments, and the finding class in GO_FUZZ_CLASS env var.
+// Failures of the command itself are logged and the crasher is stored.
+func runTriageCmd(a *NewCrasherArgs) int {
+	dir, err := ioutil.TempDir("", "go-fuzz-triage")
+	if err != nil {
+		log.Printf("failed to create triage dir: %v", err)
+		return triageStore
+	}
+	defer os.RemoveAll(dir)
+	input := filepath.Join(dir, "input")
+	output := filepath.Join(dir, "output")
+	if err := ioutil.WriteFile(input, a.Data, 0660); err != nil {
+		log.Printf("failed to write triage input: %v", err)
+		return triageStore
+	}
+	if err := ioutil.WriteFile(output, a.Error, 0660); err != nil {
+		log.Printf("failed to write triage output: %v", err)
+		return triageStore
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), triageTimeout)
+	defer cancel()
+	cmd := shellCommand(ctx, *flagTriageCmd, input, output)
+	cmd.Env = append(os.Environ(), "GO_FUZZ_CLASS="+findingClass(a))
+	out, err := cmd.CombinedOutput()
+	if *flagV >= 1 && len(out) != 0 {
+		log.Printf("triage command output:\n%s", out)
+	}
+	if err == nil {
+		return triageStore
+	}
+	if exit, ok := err.(*exec.ExitError); ok && ctx.Err() == nil {
+		switch code := exit.ExitCode(); code {
+		case triageSuppress, triageDrop:
+			return code
+		}
+	}
+	log.Printf("triage command failed: %v\n%s", err, out)
+	return triageStore
+}
@@ -62,7 +62,6 @@ func BuildVerse(oldv *Verse, data []byte) *Verse {
 	b.Visit(func(n Node) {
 		newv.allNodes = append(newv.allNodes, n)
 	})
-	newv.r = pcg.New()
 	return newv
 }
 
@@ -468,7 +467,7 @@ func (n *BlockNode) Generate(w io.Writer, v *Verse) {
 type Verse struct {
 	blocks   []*BlockNode
 	allNodes []Node
-	r        *pcg.Rand
+	r        *pcg.Rand // set only for the duration of Rhyme
 }
 
 func (v *Verse) Print(w io.Writer) {
@@ -478,9 +477,14 @@ func (v *Verse) Print(w io.Writer) {
 	}
 }
 
-func (v *Verse) Rhyme() []byte {
+// Rhyme generates new data of a similar structure.
+// All random choices are made with r, so the result is reproducible if r is seeded.
+// Verse is shared between workers, concurrent calls must use different rs.
+func (v *Verse) Rhyme(r *pcg.Rand) []byte {
+	v1 := *v
+	v1.r = r
 	buf := &bytes.Buffer{}
-	v.blocks[v.Rand(len(v.blocks))].Generate(buf, v)
+	v1.blocks[v1.Rand(len(v1.blocks))].Generate(buf, &v1)
 	return buf.Bytes()
 }
 
@@ -4,8 +4,11 @@
 package versifier
 
 import (
+	"bytes"
 	"os"
 	"testing"
+
+	"github.com/dvyukov/go-fuzz/go-fuzz/internal/pcg"
 )
 
 func dump(data string) {
@@ -33,3 +36,15 @@ func TestKeyValue(t *testing.T) {
 	dump(`a=1 a=b   2  (aa=bb) a bb:cc:dd,a=b,c=d,e=f`)
 	dump(`:a`)
 }
+
+func TestRhymeSeeded(t *testing.T) {
+	v := BuildVerse(nil, []byte(`{"f1": "v1", "f2": [1, 2.0, 3e3], "f3": "v3"}`))
+	r1 := pcg.NewSeeded(1, 2)
+	r2 := pcg.NewSeeded(1, 2)
+	for i := 0; i < 100; i++ {
+		d1, d2 := v.Rhyme(r1), v.Rhyme(r2)
+		if !bytes.Equal(d1, d2) {
+			t.Fatalf("iteration %v: rhymes with the same seed differ:\n%q\n%q", i, d1, d2)
+		}
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"log"
 	"math/bits"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -36,6 +37,7 @@ const (
 	execSmash
 	execSonar
 	execSonarHint
+	execRevive
 	execTotal
 	execCount
 )
@@ -51,10 +53,12 @@ type Worker struct {
 
 	triageQueue  []CoordinatorInput
 	crasherQueue []NewCrasherArgs
+	current      []byte // triage input or crasher being processed, already removed from the queues
 
-	lastSync time.Time
-	stats    Stats
-	execs    [execCount]uint64
+	lastSync    time.Time
+	stats       Stats
+	execs       [execCount]uint64
+	sonarPeriod int // current number of fuzzing iterations per sonar run
 }
 
 type Input struct {
@@ -62,16 +66,29 @@ type Input struct {
 	data            []byte
 	cover           []byte
 	coverSize       int
+	coverEdges      []uint16 // indices of non-zero cover entries, used by -schedule=rare
 	res             int
 	depth           int
 	typ             execType
 	execTime        uint64
 	favored         bool
+	fresh           bool // discovered during this run, as opposed to restored from workdir
 	score           int
 	runningScoreSum int
 }
 
+// fuzzed says if the input was produced by mutating other inputs
+// rather than received from corpus, imports, /inject or -revive.
+func (inp *Input) fuzzed() bool {
+	switch inp.typ {
+	case execBootstrap, execCorpus, execRevive:
+		return false
+	}
+	return true
+}
+
 func workerMain() {
+	initCgroups()
 	zipr, err := zip.OpenReader(*flagBin)
 	if err != nil {
 		log.Fatalf("failed to open bin file: %v", err)
@@ -151,14 +168,18 @@ func workerMain() {
 		log.Fatalf("internal consistency error, please file an issue: too many fuzz functions: %v", metadata.Funcs)
 	}
 
-	shutdownCleanup = append(shutdownCleanup, cleanup)
+	addShutdownCleanup(cleanup)
 
 	hub := newHub(metadata)
+	if *flagCalibrate > 0 {
+		hub.noiseMask = calibrateNoise(coverBin, uint8(fnidx), *flagCalibrate)
+	}
 	for i := 0; i < *flagProcs; i++ {
 		w := &Worker{
-			id:      i,
-			hub:     hub,
-			mutator: newMutator(),
+			id:          i,
+			hub:         hub,
+			mutator:     newMutator(hub.seed, uint64(hub.id)<<16|uint64(i)),
+			sonarPeriod: *flagSonarRate,
 		}
 		w.coverBin = newTestBinary(coverBin, w.periodicCheck, &w.stats, uint8(fnidx))
 		w.sonarBin = newTestBinary(sonarBin, w.periodicCheck, &w.stats, uint8(fnidx))
@@ -172,13 +193,22 @@ func (w *Worker) loop() {
 		if len(w.crasherQueue) > 0 {
 			n := len(w.crasherQueue) - 1
 			crash := w.crasherQueue[n]
-			w.crasherQueue[n] = NewCrasherArgs{}
-			w.crasherQueue = w.crasherQueue[:n]
-			if *flagV >= 2 {
-				log.Printf("worker %v processes crasher [%v]%v", w.id, len(crash.Data), hash(crash.Data))
+			// Hanging inputs are not minimized, so they don't need a minimization slot.
+			// If all slots are busy, continue fuzzing and retry later.
+			if crash.Hanging || w.hub.startMinimize() {
+				w.crasherQueue[n] = NewCrasherArgs{}
+				w.crasherQueue = w.crasherQueue[:n]
+				if *flagV >= 2 {
+					log.Printf("worker %v processes crasher [%v]%v", w.id, len(crash.Data), hash(crash.Data))
+				}
+				w.current = crash.Data
+				w.processCrasher(crash)
+				w.current = nil
+				if !crash.Hanging {
+					w.hub.finishMinimize()
+				}
+				continue
 			}
-			w.processCrasher(crash)
-			continue
 		}
 
 		select {
@@ -186,7 +216,9 @@ func (w *Worker) loop() {
 			if *flagV >= 2 {
 				log.Printf("worker %v triages coordinator input [%v]%v minimized=%v smashed=%v", w.id, len(input.Data), hash(input.Data), input.Minimized, input.Smashed)
 			}
+			w.current = input.Data
 			w.triageInput(input)
+			w.current = nil
 			for {
 				x := atomic.LoadUint32(&w.hub.initialTriage)
 				if x == 0 || atomic.CompareAndSwapUint32(&w.hub.initialTriage, x, x-1) {
@@ -229,26 +261,30 @@ func (w *Worker) loop() {
 		iter++
 		if iter%10 != 0 || ro.verse == nil {
 			data, depth := w.mutator.generate(ro)
-			// Every 1000-th iteration goes to sonar.
+			// Every sonarPeriod-th iteration goes to sonar.
 			fuzzSonarIter++
-			if *flagSonar && fuzzSonarIter%1000 == 0 {
+			if *flagSonar && fuzzSonarIter >= w.sonarPeriod {
 				// TODO: ensure that generated hint inputs does not actually take 99% of time.
+				fuzzSonarIter = 0
 				sonar := w.testInputSonar(data, depth)
+				queued := len(w.triageQueue)
 				w.processSonarData(data, sonar, depth, false)
+				w.adjustSonarPeriod(len(w.triageQueue) > queued)
 			} else {
 				// Plain old blind fuzzing.
 				w.testInput(data, depth, execFuzz)
 			}
 		} else {
 			// 1 out of 10 iterations goes to versifier.
-			data := ro.verse.Rhyme()
+			data := ro.verse.Rhyme(w.mutator.r)
 			const maxSize = MaxInputSize - 5*SonarMaxLen // need some gap for sonar replacements
 			if len(data) > maxSize {
 				data = data[:maxSize]
 			}
-			// Every 100-th versifier input goes to sonar.
+			// Versifier inputs go to sonar 10 times more frequently.
 			versifierSonarIter++
-			if *flagSonar && versifierSonarIter%100 == 0 {
+			if *flagSonar && versifierSonarIter >= (w.sonarPeriod+9)/10 {
+				versifierSonarIter = 0
 				sonar := w.testInputSonar(data, 0)
 				w.processSonarData(data, sonar, 0, false)
 			} else {
@@ -259,6 +295,27 @@ func (w *Worker) loop() {
 	w.shutdown()
 }
 
+// adjustSonarPeriod adapts frequency of sonar runs to their productivity.
+// A sonar run that yielded new interesting inputs halves the period,
+// an unproductive run slowly increases it. The period stays within
+// 10x of -sonarrate in both directions.
+func (w *Worker) adjustSonarPeriod(productive bool) {
+	if productive {
+		w.sonarPeriod /= 2
+	} else {
+		w.sonarPeriod += w.sonarPeriod/8 + 1
+	}
+	if lo := *flagSonarRate / 10; w.sonarPeriod < lo {
+		w.sonarPeriod = lo
+	}
+	if hi := *flagSonarRate * 10; w.sonarPeriod > hi {
+		w.sonarPeriod = hi
+	}
+	if w.sonarPeriod < 1 {
+		w.sonarPeriod = 1
+	}
+}
+
 // triageInput processes every new input.
 // It calculates per-input metrics like execution time, coverage mask,
 // and minimizes the input to the minimal input with the same coverage.
@@ -275,7 +332,7 @@ func (w *Worker) triageInput(input CoordinatorInput) {
 	// Calculate min exec time, min coverage and max result of 3 runs.
 	for i := 0; i < 3; i++ {
 		w.execs[execTriageInput]++
-		res, ns, cover, _, output, crashed, hanged := w.coverBin.test(inp.data)
+		res, ns, cover, _, output, crashed, hanged := w.test(w.coverBin, inp.data, execTriageInput)
 		if crashed {
 			// Inputs in corpus should not crash.
 			w.noteCrasher(inp.data, output, hanged)
@@ -299,6 +356,11 @@ func (w *Worker) triageInput(input CoordinatorInput) {
 			inp.execTime = ns
 		}
 	}
+	if input.Type == execRevive {
+		// Old crasher that does not crash anymore (see -revive),
+		// it is a corpus candidate regardless of coverage.
+		inp.mine = true
+	}
 	if !input.Minimized {
 		inp.mine = true
 		ro := w.hub.ro.Load().(*ROData)
@@ -324,9 +386,12 @@ func (w *Worker) triageInput(input CoordinatorInput) {
 		w.smash(inp.data, inp.depth)
 	}
 	inp.coverSize = 0
-	for _, v := range inp.cover {
+	for i, v := range inp.cover {
 		if v != 0 {
 			inp.coverSize++
+			if *flagSchedule == scheduleRare {
+				inp.coverEdges = append(inp.coverEdges, uint16(i))
+			}
 		}
 	}
 	w.hub.newInputC <- inp
@@ -334,6 +399,9 @@ func (w *Worker) triageInput(input CoordinatorInput) {
 
 // processCrasher minimizes new crashers and sends them to the hub.
 func (w *Worker) processCrasher(crash NewCrasherArgs) {
+	if crash.Hanging && !w.verifyHang(&crash) {
+		return
+	}
 	// Hanging inputs can take very long time to minimize.
 	if !crash.Hanging {
 		crash.Data = w.minimizeInput(crash.Data, true, func(candidate, cover, output []byte, res int, crashed, hanged bool) bool {
@@ -349,30 +417,101 @@ func (w *Worker) processCrasher(crash NewCrasherArgs) {
 			return true
 		})
 	}
+	if *flagConfirm > 0 && !w.confirmCrasher(crash) {
+		return
+	}
+	if crash.Hanging {
+		// The stored output comes from the replay in verifyHang.
+		crash.Env = testeeEnv(hangTraceback())
+	} else {
+		crash.Env = testeeEnv("")
+	}
 	w.hub.newCrasherC <- crash
 }
 
+// confirmCrasher replays the crasher on -confirm fresh testee processes
+// and reports whether it failed the same way every time. This filters out
+// crashes caused by the environment rather than by the input.
+func (w *Worker) confirmCrasher(crash NewCrasherArgs) bool {
+	timeout := time.Duration(*flagTimeout) * time.Second
+	if crash.Hanging {
+		timeout *= 2 // same as in verifyHang
+	}
+	for i := 0; i < *flagConfirm; i++ {
+		w.coverBin.restart()
+		w.execs[execTriageInput]++
+		start := time.Now()
+		_, _, _, _, output, crashed, hanged := w.coverBin.testTimeout(crash.Data, timeout)
+		w.stats.execTime[execTriageInput] += uint64(time.Since(start))
+		if !crashed || hanged != crash.Hanging || !hanged && !bytes.Equal(extractSuppression(output), crash.Suppression) {
+			log.Printf("worker %v: crasher [%v]%v reproduced %v out of %v times, dropping it",
+				w.id, len(crash.Data), hash(crash.Data), i, *flagConfirm)
+			return false
+		}
+	}
+	return true
+}
+
+// verifyHang replays a hanging input with doubled timeout on a fresh testee.
+// Hangs can be caused by machine overload rather than by the input itself,
+// only hangs that reproduce are reported. The replay dumps stacks of all
+// goroutines, its output replaces the original one.
+func (w *Worker) verifyHang(crash *NewCrasherArgs) bool {
+	w.execs[execTriageInput]++
+	start := time.Now()
+	_, _, _, _, output, crashed, hanged := w.coverBin.testHang(crash.Data, 2*time.Duration(*flagTimeout)*time.Second)
+	w.stats.execTime[execTriageInput] += uint64(time.Since(start))
+	if hanged {
+		crash.Error = output
+		return true
+	}
+	if crashed {
+		// Not a hang, but still a crash.
+		w.noteCrasher(crash.Data, output, false)
+		return false
+	}
+	log.Printf("worker %v: hang on input [%v]%v does not reproduce with doubled timeout, machine is probably overloaded",
+		w.id, len(crash.Data), hash(crash.Data))
+	return false
+}
+
 // minimizeInput applies series of minimizing transformations to data
 // and asks pred whether the input is equivalent to the original one or not.
 func (w *Worker) minimizeInput(data []byte, canonicalize bool, pred func(candidate, cover, output []byte, result int, crashed, hanged bool) bool) []byte {
 	res := make([]byte, len(data))
 	copy(res, data)
 	start := time.Now()
-	stat := &w.execs[execMinimizeInput]
+	limit := *flagMinimize
+	typ := execMinimizeInput
 	if canonicalize {
-		stat = &w.execs[execMinimizeCrasher]
+		typ = execMinimizeCrasher
+		if *flagMinimizeCrash != 0 {
+			limit = *flagMinimizeCrash
+		}
+	}
+	// Different transformations frequently produce the same candidate
+	// (e.g. removal of any byte in a run of equal bytes), so cache pred results.
+	tested := make(map[Sig]bool)
+	try := func(candidate []byte) bool {
+		sig := hash(candidate)
+		if ok, seen := tested[sig]; seen {
+			return ok
+		}
+		w.execs[typ]++
+		result, _, cover, _, output, crashed, hanged := w.test(w.coverBin, candidate, typ)
+		ok := pred(candidate, cover, output, result, crashed, hanged)
+		tested[sig] = ok
+		return ok
 	}
 
 	// First, try to cut tail.
 	for n := 1024; n != 0; n /= 2 {
 		for len(res) > n {
-			if time.Since(start) > *flagMinimize {
+			if time.Since(start) > limit {
 				return res
 			}
 			candidate := res[:len(res)-n]
-			*stat++
-			result, _, cover, _, output, crashed, hanged := w.coverBin.test(candidate)
-			if !pred(candidate, cover, output, result, crashed, hanged) {
+			if !try(candidate) {
 				break
 			}
 			res = candidate
@@ -382,15 +521,13 @@ func (w *Worker) minimizeInput(data []byte, canonicalize bool, pred func(candida
 	// Then, try to remove each individual byte.
 	tmp := make([]byte, len(res))
 	for i := 0; i < len(res); i++ {
-		if time.Since(start) > *flagMinimize {
+		if time.Since(start) > limit {
 			return res
 		}
 		candidate := tmp[:len(res)-1]
 		copy(candidate[:i], res[:i])
 		copy(candidate[i:], res[i+1:])
-		*stat++
-		result, _, cover, _, output, crashed, hanged := w.coverBin.test(candidate)
-		if !pred(candidate, cover, output, result, crashed, hanged) {
+		if !try(candidate) {
 			continue
 		}
 		res = makeCopy(candidate)
@@ -401,14 +538,12 @@ func (w *Worker) minimizeInput(data []byte, canonicalize bool, pred func(candida
 	for i := 0; i < len(res)-1; i++ {
 		copy(tmp, res[:i])
 		for j := len(res); j > i+1; j-- {
-			if time.Since(start) > *flagMinimize {
+			if time.Since(start) > limit {
 				return res
 			}
 			candidate := tmp[:len(res)-j+i]
 			copy(candidate[i:], res[j:])
-			*stat++
-			result, _, cover, _, output, crashed, hanged := w.coverBin.test(candidate)
-			if !pred(candidate, cover, output, result, crashed, hanged) {
+			if !try(candidate) {
 				continue
 			}
 			res = makeCopy(candidate)
@@ -422,15 +557,13 @@ func (w *Worker) minimizeInput(data []byte, canonicalize bool, pred func(candida
 			if res[i] == '0' {
 				continue
 			}
-			if time.Since(start) > *flagMinimize {
+			if time.Since(start) > limit {
 				return res
 			}
 			candidate := tmp[:len(res)]
 			copy(candidate, res)
 			candidate[i] = '0'
-			*stat++
-			result, _, cover, _, output, crashed, hanged := w.coverBin.test(candidate)
-			if !pred(candidate, cover, output, result, crashed, hanged) {
+			if !try(candidate) {
 				continue
 			}
 			res = makeCopy(candidate)
@@ -603,7 +736,7 @@ func (w *Worker) testInputImpl(bin *TestBinary, data []byte, depth int, typ exec
 		}
 	}
 	w.execs[typ]++
-	res, _, cover, sonar, output, crashed, hanged := bin.test(data)
+	res, _, cover, sonar, output, crashed, hanged := w.test(bin, data, typ)
 	if crashed {
 		w.noteCrasher(data, output, hanged)
 		return nil
@@ -612,6 +745,64 @@ func (w *Worker) testInputImpl(bin *TestBinary, data []byte, depth int, typ exec
 	return sonar
 }
 
+// test executes data on bin and accounts the execution time to typ.
+func (w *Worker) test(bin *TestBinary, data []byte, typ execType) (res int, ns uint64, cover, sonar, output []byte, crashed, hanged bool) {
+	start := time.Now()
+	res, ns, cover, sonar, output, crashed, hanged = bin.test(data)
+	w.stats.execTime[typ] += uint64(time.Since(start))
+	if !crashed {
+		w.stats.latency[latencyBucket(ns)]++
+		w.stats.latencyNs += ns
+		// Sonar instrumentation makes runs slower, so only cover runs are comparable.
+		if *flagSlowest > 0 && bin == w.coverBin {
+			w.hub.noteSlow(data, ns)
+		}
+		for _, idx := range w.hub.noiseMask {
+			cover[idx] = 0
+		}
+	}
+	return
+}
+
+// calibrateNoise runs the empty input several times and returns indices of
+// cover table entries that differ between the runs. Such entries are caused
+// by background activity in the testee (GC, timers, background goroutines)
+// rather than by the input, so they must not be treated as new coverage.
+func calibrateNoise(fileName string, fnidx uint8, runs int) []int {
+	var stats Stats
+	bin := newTestBinary(fileName, func() {}, &stats, fnidx)
+	defer bin.close()
+	var first []byte
+	noisy := make([]bool, CoverSize)
+	for i := 0; i < runs; i++ {
+		_, _, cover, _, _, crashed, _ := bin.test(nil)
+		if crashed {
+			log.Printf("calibration: empty input crashes, skipping calibration")
+			return nil
+		}
+		if first == nil {
+			first = make([]byte, CoverSize)
+			for j, c := range cover {
+				first[j] = roundUpCover(c)
+			}
+			continue
+		}
+		for j, c := range cover {
+			if roundUpCover(c) != first[j] {
+				noisy[j] = true
+			}
+		}
+	}
+	var mask []int
+	for j, n := range noisy {
+		if n {
+			mask = append(mask, j)
+		}
+	}
+	log.Printf("calibration: %v noisy cover entries ignored", len(mask))
+	return mask
+}
+
 func (w *Worker) noteNewInput(data, cover []byte, res, depth int, typ execType) {
 	if res < 0 {
 		// User said to not add this input to corpus.
@@ -647,8 +838,7 @@ func (w *Worker) periodicCheck() {
 	w.execs[execTotal] += w.stats.execs
 	w.lastSync = time.Now()
 	w.hub.syncC <- w.stats
-	w.stats.execs = 0
-	w.stats.restarts = 0
+	w.stats = Stats{}
 	if *flagV >= 2 {
 		log.Printf("worker %v: triageq=%v execs=%v mininp=%v mincrash=%v triage=%v fuzz=%v versifier=%v smash=%v sonar=%v hint=%v",
 			w.id, len(w.triageQueue),
@@ -660,10 +850,41 @@ func (w *Worker) periodicCheck() {
 
 // shutdown cleanups after worker, it is not guaranteed to be called.
 func (w *Worker) shutdown() {
+	w.saveRecovery()
 	w.coverBin.close()
 	w.sonarBin.close()
 }
 
+// saveRecovery dumps inputs that the worker has not finished processing
+// into workdir/recovery, so that they are re-executed on the next start.
+// It is called from periodicCheck, in the middle of processing w.current.
+func (w *Worker) saveRecovery() {
+	var inputs [][]byte
+	if w.current != nil {
+		inputs = append(inputs, w.current)
+	}
+	for _, input := range w.triageQueue {
+		inputs = append(inputs, input.Data)
+	}
+	for _, crash := range w.crasherQueue {
+		inputs = append(inputs, crash.Data)
+	}
+	if len(inputs) == 0 {
+		return
+	}
+	dir := filepath.Join(*flagWorkdir, "recovery")
+	os.MkdirAll(dir, 0770)
+	for _, data := range inputs {
+		a := Artifact{data, 0, false}
+		if err := ioutil.WriteFile(persistentFilename(dir, a, hash(data)), data, 0660); err != nil {
+			log.Printf("failed to write file: %v", err)
+		}
+	}
+	if *flagV >= 1 {
+		log.Printf("worker %v saved %v in-flight inputs for recovery", w.id, len(inputs))
+	}
+}
+
 func extractSuppression(out []byte) []byte {
 	var supp []byte
 	seenPanic := false
@@ -25,3 +25,19 @@ func TestIncrementDecrement(t *testing.T) {
 		}
 	}
 }
+
+func TestAdjustSonarPeriod(t *testing.T) {
+	w := &Worker{sonarPeriod: *flagSonarRate}
+	for i := 0; i < 100; i++ {
+		w.adjustSonarPeriod(true)
+	}
+	if want := *flagSonarRate / 10; w.sonarPeriod != want {
+		t.Fatalf("sonar period after productive runs = %v, want %v", w.sonarPeriod, want)
+	}
+	for i := 0; i < 1000; i++ {
+		w.adjustSonarPeriod(false)
+	}
+	if want := *flagSonarRate * 10; w.sonarPeriod != want {
+		t.Fatalf("sonar period after unproductive runs = %v, want %v", w.sonarPeriod, want)
+	}
+}